@@ -0,0 +1,151 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"hash"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// HMAC hash algorithms, sent as the "alg" parameter. Requests without it
+// use SHA256.
+const (
+	SHA256   = "sha256"
+	SHA512   = "sha512"
+	SHA3_256 = "sha3-256"
+)
+
+var algorithms = struct {
+	sync.RWMutex
+	m map[string]func() hash.Hash
+}{m: map[string]func() hash.Hash{
+	SHA256:   sha256.New,
+	SHA512:   sha512.New,
+	SHA3_256: func() hash.Hash { return sha3.New256() },
+}}
+
+// RegisterAlgorithm makes the hash algorithm name available to signers
+// and validators, e.g. BLAKE2b (see package hancock/blake2), which isn't in
+// the standard library.
+func RegisterAlgorithm(name string, fn func() hash.Hash) {
+	algorithms.Lock()
+	defer algorithms.Unlock()
+	algorithms.m[name] = fn
+}
+
+// algorithm returns the hash of the algorithm name, "" being SHA256.
+func algorithm(name string) (func() hash.Hash, bool) {
+	if name == "" {
+		name = SHA256
+	}
+	algorithms.RLock()
+	defer algorithms.RUnlock()
+	fn, ok := algorithms.m[name]
+	return fn, ok
+}
+
+// Algorithms returns the registered algorithms, and the asymmetric ones,
+// sorted.
+func Algorithms() []string {
+	algorithms.RLock()
+	defer algorithms.RUnlock()
+	names := make([]string, 0, len(algorithms.m)+len(schemes))
+	for name := range algorithms.m {
+		names = append(names, name)
+	}
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithAlgorithms only accepts signatures made with the named algorithms.
+// Every registered algorithm is accepted by default.
+func WithAlgorithms(names ...string) Option {
+	return func(v *Validator) {
+		v.algs = append([]string(nil), names...)
+	}
+}
+
+// SignAlgorithm signs with the named algorithm, which must be registered.
+func SignAlgorithm(name string) SignOption {
+	return func(c *signConfig) {
+		c.alg = name
+	}
+}
+
+// SignAlgorithms signs with each of the named algorithms, e.g. SHA256 and
+// SHA512 while migrating from one to the other. Validators accept requests
+// verified by any of them they accept (see WithAlgorithms), counting each
+// that did in Status, so the validator's algorithms can be narrowed once
+// clients moved on.
+func SignAlgorithms(names ...string) SignOption {
+	return func(c *signConfig) {
+		c.alg = strings.Join(names, ",")
+	}
+}
+
+// namedHash is an algorithm's hash.
+type namedHash struct {
+	name string
+	fn   func() hash.Hash
+}
+
+// acceptedAlgorithms returns the algorithms v accepts.
+func (v *Validator) acceptedAlgorithms() []string {
+	if v.algs == nil {
+		return Algorithms()
+	}
+	var names []string
+	for _, name := range v.algs {
+		if _, ok := algorithm(name); ok || schemes[name] != nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// hashFor returns the hash of the algorithm name, when v accepts it.
+func (v *Validator) hashFor(name string) (func() hash.Hash, bool) {
+	if name == "" {
+		name = SHA256
+	}
+	if v.algs != nil && !contains(v.algs, name) {
+		return nil, false
+	}
+	return algorithm(name)
+}
+
+// hashesFor returns the hashes of the comma separated algorithms names,
+// those v accepts, once each. The names are sent unsigned, so lists longer
+// than the algorithms v accepts are rejected, reporting false, before
+// they're split.
+func (v *Validator) hashesFor(names string) ([]namedHash, bool) {
+	if strings.Count(names, ",") >= len(v.acceptedAlgorithms()) {
+		return nil, false
+	}
+	var hashes []namedHash
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(names, ",") {
+		n := name
+		if n == "" {
+			n = SHA256
+		}
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		if alg, ok := v.hashFor(name); ok {
+			hashes = append(hashes, namedHash{name, alg})
+		}
+	}
+	return hashes, true
+}
@@ -0,0 +1,54 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAlgorithmLists(t *testing.T) {
+	keys := WithKeyInfo(keyMap(map[string]*KeyInfo{
+		"key": {Key: "key", Secret: "secret", Previous: []string{"old"}, Expires: 300},
+	}))
+	// withAlg returns u with its alg parameter replaced by alg, unsigned.
+	withAlg := func(u, alg string) string {
+		pu, _ := url.Parse(u)
+		q := pu.Query()
+		q.Set("alg", alg)
+		pu.RawQuery = q.Encode()
+		return pu.String()
+	}
+	signed := Sign(http.MethodGet, "key", "secret", "/", nil)
+	tests := []struct {
+		name   string
+		opts   []Option
+		url    string
+		status int
+	}{
+		{"single", nil, signed, 0},
+		// Each algorithm signed with each of the rotated secrets.
+		{"bundle", nil, Sign(http.MethodGet, "key", "secret", "/", nil, SignAlgorithms(SHA256, SHA512, SHA3_256), SignAlsoWith("old")), 0},
+		{"duplicates", nil, withAlg(signed, "sha256,sha256"), http.StatusUnauthorized},
+		{"long list", nil, withAlg(signed, strings.Repeat("sha256,", 20000)), http.StatusBadRequest},
+		{"past accepted", []Option{WithAlgorithms(SHA256)}, Sign(http.MethodGet, "key", "secret", "/", nil, SignAlgorithms(SHA256, SHA512)), http.StatusBadRequest},
+		{"too many signatures", nil, signed + strings.Repeat("&data=x", 2), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		v := NewValidator(nil, append([]Option{keys}, tt.opts...)...)
+		_, err := v.Validate(httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if status(err) != tt.status {
+			t.Errorf("%s: status %d, want %d (%v)", tt.name, status(err), tt.status, err)
+		}
+	}
+
+	v := NewValidator(nil, keys)
+	if hashes, ok := v.hashesFor("sha256,,sha512,sha256"); !ok || len(hashes) != 2 {
+		t.Errorf("hashesFor = %d hashes, %v; want 2 deduped", len(hashes), ok)
+	}
+}
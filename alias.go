@@ -0,0 +1,124 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// aliasLen is the number of hex characters kept from a key's fingerprint.
+const aliasLen = 8
+
+// maxAliases bounds the alias → key mapping; once reached the mapping is reset.
+const maxAliases = 10000
+
+// Aliases maps API keys to short, non-reversible aliases that are safe to
+// log, and remembers the mapping of known keys (see Remember) so authorized
+// callers can look them back up.
+type Aliases struct {
+	mu   sync.RWMutex
+	salt []byte
+	keys map[string]string
+}
+
+// DefaultAliases is used for every log line and error emitted by the package.
+var DefaultAliases = NewAliases(nil)
+
+// NewAliases returns an alias mapping keyed by salt.
+// A nil salt produces plain SHA-256 fingerprints.
+func NewAliases(salt []byte) *Aliases {
+	return &Aliases{salt: salt, keys: make(map[string]string)}
+}
+
+// Alias returns the alias for key. Keys are hashed on every call rather
+// than remembered, as they're attacker chosen until looked up.
+func (a *Aliases) Alias(key string) string {
+	if key == "" {
+		return ""
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.alias(key)
+}
+
+func (a *Aliases) alias(key string) string {
+	var sum []byte
+	if a.salt == nil {
+		s := sha256.Sum256([]byte(key))
+		sum = s[:]
+	} else {
+		h := hmac.New(sha256.New, a.salt)
+		h.Write([]byte(key))
+		sum = h.Sum(nil)
+	}
+	return hex.EncodeToString(sum)[:aliasLen]
+}
+
+// Remember returns the alias for key, a known key, remembering it for
+// Lookup. Validators remember the keys they look up.
+func (a *Aliases) Remember(key string) string {
+	if key == "" {
+		return ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	alias := a.alias(key)
+	if _, ok := a.keys[alias]; !ok && len(a.keys) >= maxAliases {
+		a.keys = make(map[string]string)
+	}
+	a.keys[alias] = key
+	return alias
+}
+
+// Lookup returns the key remembered as alias.
+func (a *Aliases) Lookup(alias string) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	key, ok := a.keys[alias]
+	return key, ok
+}
+
+// Rotate switches to a new salt and forgets all previous aliases.
+func (a *Aliases) Rotate(salt []byte) {
+	a.mu.Lock()
+	a.salt = salt
+	a.keys = make(map[string]string)
+	a.mu.Unlock()
+}
+
+// KeyAlias returns the DefaultAliases alias for key.
+func KeyAlias(key string) string {
+	return DefaultAliases.Alias(key)
+}
+
+// aliasURI replaces the API key within a request URI with its alias, and
+// redacts its signature.
+func aliasURI(uri string, p Params) string {
+	i := strings.IndexByte(uri, '?')
+	if i < 0 {
+		return uri
+	}
+	pairs := strings.Split(uri[i+1:], "&")
+	for n, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case p.APIKey:
+			if key, err := url.QueryUnescape(v); err == nil {
+				pairs[n] = k + "=" + KeyAlias(key)
+			}
+		case p.Signature:
+			pairs[n] = k + "=" + Redacted
+		}
+	}
+	return uri[:i+1] + strings.Join(pairs, "&")
+}
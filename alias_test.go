@@ -0,0 +1,54 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	v := NewValidator(nil, WithKeyInfo(keyMap(map[string]*KeyInfo{"known": {Key: "known", Secret: "secret"}})))
+	tests := []struct {
+		key        string
+		remembered bool
+	}{
+		{"known", true},
+		// Attacker chosen keys aren't remembered.
+		{"unknown", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, tt.key, "secret", "/", nil), nil)
+		v.Validate(r)
+		if _, ok := DefaultAliases.Lookup(KeyAlias(tt.key)); ok != tt.remembered {
+			t.Errorf("%s: remembered %v, want %v", tt.key, ok, tt.remembered)
+		}
+	}
+
+	a := NewAliases([]byte("salt"))
+	alias := a.Alias("key")
+	if _, ok := a.Lookup(alias); ok {
+		t.Error("Alias remembered its key")
+	}
+	if a.Remember("key") != alias {
+		t.Error("Remember and Alias differ")
+	}
+	if key, ok := a.Lookup(alias); !ok || key != "key" {
+		t.Errorf("Lookup = %q, %v", key, ok)
+	}
+}
+
+func TestAliasURI(t *testing.T) {
+	uri := aliasURI("/a?apikey=key&ts=1&data=c2ln&q=data", DefaultParams)
+	if want := "/a?apikey=" + KeyAlias("key") + "&ts=1&data=" + Redacted + "&q=data"; uri != want {
+		t.Errorf("aliasURI = %q, want %q", uri, want)
+	}
+	err := newError(http.StatusUnauthorized, httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, "key", "secret", "/", nil), nil), "")
+	if strings.Contains(err.Request.RequestURI, "apikey=key") || !strings.Contains(err.Request.RequestURI, "data="+Redacted) {
+		t.Errorf("unredacted request URI %q", err.Request.RequestURI)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+)
+
+// Asymmetric signature algorithms, sent as the "alg" parameter. Clients
+// sign with a private key (see SignQSKey), and validators only hold the
+// public key (KeyInfo.PublicKey), never a secret able to sign.
+const (
+	Ed25519 = "ed25519"
+	// ES256 is ECDSA with P-256 and SHA-256, for keys held in hardware
+	// (e.g. Secure Enclave, Android Keystore). Signatures are either DER or
+	// raw `r||s` encoded (see SignECDSARaw).
+	ES256 = "es256"
+	// PS256 is RSA-PSS with SHA-256, for keys issued by a PKI (see
+	// ParsePublicKeyPEM). Keys must be at least 2048 bits.
+	PS256 = "ps256"
+)
+
+// minRSABits is the smallest RSA key accepted.
+const minRSABits = 2048
+
+var pssOptions = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+
+// ErrKeyType is returned when signing with an unsupported private key.
+var ErrKeyType = errors.New("hancock: unsupported key type")
+
+// schemes verify the signatures of the asymmetric algorithms.
+var schemes = map[string]func(pub crypto.PublicKey, msg, sig []byte) bool{
+	Ed25519: verifyEd25519,
+	ES256:   verifyES256,
+	PS256:   verifyPS256,
+}
+
+func verifyEd25519(pub crypto.PublicKey, msg, sig []byte) bool {
+	k, ok := pub.(ed25519.PublicKey)
+	return ok && len(k) == ed25519.PublicKeySize && ed25519.Verify(k, msg, sig)
+}
+
+func verifyES256(pub crypto.PublicKey, msg, sig []byte) bool {
+	k, ok := pub.(*ecdsa.PublicKey)
+	if !ok || k.Curve != elliptic.P256() {
+		return false
+	}
+	digest := sha256.Sum256(msg)
+	if len(sig) == 64 {
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if ecdsa.Verify(k, digest[:], r, s) {
+			return true
+		}
+	}
+	return ecdsa.VerifyASN1(k, digest[:], sig)
+}
+
+func verifyPS256(pub crypto.PublicKey, msg, sig []byte) bool {
+	k, ok := pub.(*rsa.PublicKey)
+	if !ok || k.N.BitLen() < minRSABits {
+		return false
+	}
+	digest := sha256.Sum256(msg)
+	return rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, pssOptions) == nil
+}
+
+// SignECDSARaw encodes ECDSA signatures as raw `r||s`, as produced by
+// WebCrypto, instead of DER.
+func SignECDSARaw() SignOption {
+	return func(c *signConfig) {
+		c.rawECDSA = true
+	}
+}
+
+// signAsymmetric returns priv's signature of msg with alg.
+func signAsymmetric(priv crypto.Signer, alg string, msg []byte, c *signConfig) ([]byte, error) {
+	switch alg {
+	case Ed25519:
+		return priv.Sign(rand.Reader, msg, crypto.Hash(0))
+	case ES256:
+		digest := sha256.Sum256(msg)
+		sig, err := priv.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil || !c.rawECDSA {
+			return sig, err
+		}
+		return rawECDSA(sig)
+	case PS256:
+		digest := sha256.Sum256(msg)
+		return priv.Sign(rand.Reader, digest[:], pssOptions)
+	}
+	return nil, ErrKeyType
+}
+
+// rawECDSA converts a DER P-256 signature to `r||s`.
+func rawECDSA(der []byte) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, err
+	}
+	raw := make([]byte, 64)
+	sig.R.FillBytes(raw[:32])
+	sig.S.FillBytes(raw[32:])
+	return raw, nil
+}
+
+// keyAlgorithm returns the algorithm of priv.
+func keyAlgorithm(priv crypto.Signer) (string, error) {
+	switch pub := priv.Public().(type) {
+	case ed25519.PublicKey:
+		return Ed25519, nil
+	case *ecdsa.PublicKey:
+		if pub.Curve == elliptic.P256() {
+			return ES256, nil
+		}
+	case *rsa.PublicKey:
+		if pub.N.BitLen() >= minRSABits {
+			return PS256, nil
+		}
+	}
+	return "", ErrKeyType
+}
+
+// SignQSKey returns a query string signed with priv (see SignQS), e.g. an
+// ed25519.PrivateKey, P-256 *ecdsa.PrivateKey or *rsa.PrivateKey, the
+// algorithm following from its type.
+func SignQSKey(method, key string, priv crypto.Signer, values url.Values, opts ...SignOption) (string, error) {
+	c := newSignConfig(opts)
+	alg, err := keyAlgorithm(priv)
+	if err != nil {
+		return "", err
+	}
+	c.alg = alg
+	v, msg := c.prepare(method, key, "", values, c.clock.Now())
+	sig, err := signAsymmetric(priv, alg, []byte(msg), c)
+	if err != nil {
+		return "", err
+	}
+	v.Add(c.params.Signature, base64.URLEncoding.EncodeToString(sig))
+	return v.Encode(), nil
+}
+
+// SignKey returns a URL signed with priv (see SignQSKey).
+func SignKey(method, key string, priv crypto.Signer, urlStr string, qs url.Values, opts ...SignOption) (string, error) {
+	if u, err := url.Parse(urlStr); err == nil {
+		opts = append([]SignOption{SignPath(u.EscapedPath())}, opts...)
+	}
+	qsStr, err := SignQSKey(method, key, priv, qs, opts...)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s?%s", urlStr, qsStr), nil
+}
+
+// ParsePublicKeyPEM returns the public key of a PEM encoded "PUBLIC KEY",
+// "RSA PUBLIC KEY" or "CERTIFICATE", e.g. issued by a partner's CA, to
+// return in KeyInfo.PublicKey. Verifying certificate chains is left to the
+// caller.
+func ParsePublicKeyPEM(b []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, ErrKeyType
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, ErrKeyType
+}
+
+// schemeFor returns the verifier of the asymmetric algorithm name, when v
+// accepts it.
+func (v *Validator) schemeFor(name string) (func(crypto.PublicKey, []byte, []byte) bool, bool) {
+	verify, ok := schemes[name]
+	if !ok || (v.algs != nil && !contains(v.algs, name)) {
+		return nil, false
+	}
+	return verify, true
+}
+
+// anyAsymmetric reports whether any of the signatures data verifies msg
+// with pub.
+func anyAsymmetric(verify func(crypto.PublicKey, []byte, []byte) bool, pub crypto.PublicKey, msg string, data []string) bool {
+	if pub == nil {
+		return false
+	}
+	ok := false
+	for _, d := range data {
+		// Unescaped '+' arrives as ' ' from the query string.
+		d = strings.NewReplacer("+", "-", " ", "-", "/", "_").Replace(strings.TrimRight(d, "="))
+		sig, err := base64.RawURLEncoding.DecodeString(d)
+		if err == nil && verify(pub, []byte(msg), sig) {
+			ok = true
+		}
+	}
+	return ok
+}
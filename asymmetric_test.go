@@ -0,0 +1,75 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAsymmetric(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := WithKeyInfo(keyMap(map[string]*KeyInfo{
+		"ed":  {Key: "ed", PublicKey: edKey.Public(), Expires: 300},
+		"ec":  {Key: "ec", PublicKey: ecKey.Public(), Expires: 300},
+		"rsa": {Key: "rsa", PublicKey: rsaKey.Public(), Expires: 300},
+	}))
+
+	tests := []struct {
+		name   string
+		key    string
+		priv   crypto.Signer
+		sign   []SignOption
+		opts   []Option
+		status int
+	}{
+		{"ed25519", "ed", edKey, nil, nil, 0},
+		{"es256", "ec", ecKey, nil, nil, 0},
+		{"es256 raw", "ec", ecKey, []SignOption{SignECDSARaw()}, nil, 0},
+		{"ps256", "rsa", rsaKey, nil, nil, 0},
+		{"other key", "ed", otherKey, nil, nil, http.StatusUnauthorized},
+		{"other algorithm", "ec", edKey, nil, nil, http.StatusUnauthorized},
+		{"not accepted", "ed", edKey, nil, []Option{WithAlgorithms(SHA256)}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		v := NewValidator(nil, append([]Option{keys}, tt.opts...)...)
+		u, err := SignKey(http.MethodGet, tt.key, tt.priv, "/a", nil, tt.sign...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		_, verr := v.Validate(httptest.NewRequest(http.MethodGet, u, nil))
+		if status(verr) != tt.status {
+			t.Errorf("%s: status %d, want %d (%v)", tt.name, status(verr), tt.status, verr)
+		}
+	}
+
+	// Keys holding only a public key can't be signed for with a secret.
+	v := NewValidator(nil, keys)
+	_, err := v.Validate(httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, "ed", "", "/a", nil), nil))
+	if status(err) == 0 {
+		t.Error("HMAC signature accepted for a public key")
+	}
+}
+
+func TestSignKeyType(t *testing.T) {
+	weak, _ := rsa.GenerateKey(rand.Reader, 1024)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	for _, priv := range []crypto.Signer{weak, p384} {
+		if _, err := SignKey(http.MethodGet, "key", priv, "/", nil); !errors.Is(err, ErrKeyType) {
+			t.Errorf("%T: err %v, want ErrKeyType", priv.Public(), err)
+		}
+	}
+}
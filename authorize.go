@@ -0,0 +1,119 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+)
+
+// Decision is an Authorizer's verdict on a request.
+type Decision struct {
+	Allow bool
+	// Reason explains a denial, in the error written to the client.
+	Reason string
+	// Obligations are conditions handlers must meet for allowed requests,
+	// e.g. "audit" or "mask-pii" (see Obligations).
+	Obligations []string
+}
+
+// Allow returns a Decision allowing a request with obligations.
+func Allow(obligations ...string) Decision {
+	return Decision{Allow: true, Obligations: obligations}
+}
+
+// Deny returns a Decision denying a request for reason.
+func Deny(reason string) Decision {
+	return Decision{Reason: reason}
+}
+
+// Authorizer decides whether the key of a validated request may make it,
+// for business rules enforced by the same middleware as the signatures.
+// r's context carries info and the request's claims (see
+// ClaimsFromContext).
+type Authorizer interface {
+	Authorize(r *http.Request, info *KeyInfo) Decision
+}
+
+// AuthorizerFunc is a function Authorizer.
+type AuthorizerFunc func(r *http.Request, info *KeyInfo) Decision
+
+// Authorize calls fn.
+func (fn AuthorizerFunc) Authorize(r *http.Request, info *KeyInfo) Decision {
+	return fn(r, info)
+}
+
+const obligationsKey contextKey = 2
+
+// WithAuthorizer adds a to the authorizers Handler runs, in order, once a
+// request's signature is valid. Each must allow it, or it's rejected with
+// a 403, their obligations are combined.
+func WithAuthorizer(a Authorizer) Option {
+	return func(v *Validator) {
+		v.authorizers = append(v.authorizers, a)
+	}
+}
+
+// Obligations returns the obligations of an authorized request.
+func Obligations(ctx context.Context) []string {
+	o, _ := ctx.Value(obligationsKey).([]string)
+	return o
+}
+
+// ScopeAuthorizer allows keys granted every one of scopes.
+func ScopeAuthorizer(scopes ...string) Authorizer {
+	return AuthorizerFunc(func(r *http.Request, info *KeyInfo) Decision {
+		for _, s := range scopes {
+			if !contains(info.Scopes, s) {
+				return Deny("missing scope " + s)
+			}
+		}
+		return Allow()
+	})
+}
+
+// AddrAuthorizer allows requests from the given prefixes (see
+// ParsePrefixes).
+func AddrAuthorizer(prefixes ...netip.Prefix) Authorizer {
+	return AuthorizerFunc(func(r *http.Request, info *KeyInfo) Decision {
+		if a, err := RemoteAddr(r); err == nil && matchAddr(prefixes, a) {
+			return Allow()
+		}
+		return Deny("remote address not allowed")
+	})
+}
+
+// MethodAuthorizer allows the methods of rules to keys granted every one of
+// their scopes, e.g. {"GET": {"read"}, "DELETE": {"admin"}}. Other methods
+// are denied.
+func MethodAuthorizer(rules map[string][]string) Authorizer {
+	return AuthorizerFunc(func(r *http.Request, info *KeyInfo) Decision {
+		scopes, ok := rules[r.Method]
+		if !ok {
+			return Deny("method " + r.Method + " not allowed")
+		}
+		return ScopeAuthorizer(scopes...).Authorize(r, info)
+	})
+}
+
+// authorize runs v's authorizers on r, returning r carrying the
+// obligations, or the error of a denial.
+func (v *Validator) authorize(r *http.Request, info *KeyInfo) (*http.Request, *Error) {
+	var obligations []string
+	for _, a := range v.authorizers {
+		d := a.Authorize(r, info)
+		if !d.Allow && d.Reason == "" {
+			return nil, v.newError(http.StatusForbidden, r, "not authorized")
+		} else if !d.Allow {
+			return nil, v.newError(http.StatusForbidden, r, "not authorized: %s", d.Reason)
+		}
+		obligations = append(obligations, d.Obligations...)
+	}
+	if obligations != nil {
+		r = r.WithContext(context.WithValue(r.Context(), obligationsKey, obligations))
+	}
+	return r, nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package blake2 registers the BLAKE2b hash algorithms with hancock,
+// keeping the core package free of dependencies outside the standard
+// library. Import it for its side effect:
+//
+//	import _ "code.minty.io/hancock/blake2"
+package blake2
+
+import (
+	"hash"
+
+	"code.minty.io/hancock"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Algorithm names, see hancock.SignAlgorithm.
+const (
+	BLAKE2b256 = "blake2b-256"
+	BLAKE2b512 = "blake2b-512"
+)
+
+func init() {
+	hancock.RegisterAlgorithm(BLAKE2b256, func() hash.Hash {
+		h, _ := blake2b.New256(nil) // only fails for oversized keys
+		return h
+	})
+	hancock.RegisterAlgorithm(BLAKE2b512, func() hash.Hash {
+		h, _ := blake2b.New512(nil)
+		return h
+	})
+}
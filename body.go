@@ -0,0 +1,134 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// BodyHash returns the digest of body signed as the "bodyhash" parameter,
+// the base64url SHA-256 of its bytes.
+func BodyHash(body io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, body); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// RequestBodyHash returns the BodyHash of r's body, replacing r.Body so
+// it can be read again by the handler.
+func RequestBodyHash(r *http.Request) (string, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return BodyHash(http.NoBody)
+	}
+	b, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	return BodyHash(bytes.NewReader(b))
+}
+
+// SignBody signs the digest of body (see BodyHash), so the payload can't
+// be tampered with. Requests must be sent with body.
+func SignBody(body []byte) SignOption {
+	return func(c *signConfig) {
+		c.body, _ = BodyHash(bytes.NewReader(body))
+	}
+}
+
+// SignBodyHash signs digest, a BodyHash computed by the caller, e.g. while
+// streaming a large body.
+func SignBodyHash(digest string) SignOption {
+	return func(c *signConfig) {
+		c.body = digest
+	}
+}
+
+// WithBodyHash requires requests with a body to sign its digest (see
+// SignBody). Signed digests are checked whether or not it's set.
+func WithBodyHash() Option {
+	return func(v *Validator) {
+		v.bodyHash = true
+	}
+}
+
+// checkBodyHash checks r's body against its signed digest.
+func (v *Validator) checkBodyHash(r *http.Request, digest string) *Error {
+	if digest == "" {
+		if v.bodyHash && hasBody(r) {
+			return v.newError(http.StatusBadRequest, r, "missing body hash")
+		}
+		return nil
+	}
+	sum, err := RequestBodyHash(r)
+	if err != nil {
+		return v.newError(http.StatusBadRequest, r, "reading body: %s", err)
+	}
+	if !hmac.Equal([]byte(sum), []byte(digest)) {
+		return v.newError(http.StatusUnauthorized, r, "body hash mismatch")
+	}
+	return nil
+}
+
+// SignMaxSize signs the largest body accepted, e.g. for upload URLs given
+// out to others. Validators reject larger bodies before reading them.
+func SignMaxSize(max int64) SignOption {
+	return func(c *signConfig) {
+		c.maxSize = strconv.FormatInt(max, 10)
+	}
+}
+
+// DefaultMaxBodySize is the largest body validators accept, unless set
+// otherwise with WithMaxBodySize.
+const DefaultMaxBodySize = 10 << 20
+
+// WithMaxBodySize sets the largest body accepted, by default
+// DefaultMaxBodySize, whether or not requests sign a max size (see
+// SignMaxSize); the smaller of the two applies. Bodies are read, e.g. to
+// check their digest, only up to it. Zero or less leaves bodies without a
+// signed max size unbounded.
+func WithMaxBodySize(max int64) Option {
+	return func(v *Validator) {
+		v.maxBody = max
+	}
+}
+
+// checkMaxSize rejects r when its body is over max, the signed max size,
+// or v's max body size. Bodies without a length are cut off at it.
+func (v *Validator) checkMaxSize(r *http.Request, max string) *Error {
+	n := v.maxBody
+	if max != "" {
+		signed, err := strconv.ParseInt(max, 10, 64)
+		if err != nil || signed < 0 {
+			return v.newError(http.StatusBadRequest, r, "invalid max size %s", max)
+		}
+		if n <= 0 || signed < n {
+			n = signed
+		}
+	}
+	if n <= 0 && max == "" {
+		return nil
+	}
+	if r.ContentLength > n {
+		return v.newError(http.StatusRequestEntityTooLarge, r, "body over %d bytes", n)
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(nil, r.Body, n)
+	}
+	return nil
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
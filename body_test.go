@@ -0,0 +1,85 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyHash(t *testing.T) {
+	const body = `{"amount": 100}`
+	tests := []struct {
+		name    string
+		carrier Carrier
+		signed  string // signed body, unsigned when empty
+		sent    string
+		max     int64
+		opts    []Option
+		status  int
+	}{
+		{"query", nil, body, body, 0, nil, 0},
+		{"authorization", AuthorizationCarrier{}, body, body, 0, nil, 0},
+		{"tampered", AuthorizationCarrier{}, body, `{"amount": 999}`, 0, nil, http.StatusUnauthorized},
+		{"unsigned", AuthorizationCarrier{}, "", body, 0, nil, 0},
+		{"required", AuthorizationCarrier{}, "", body, 0, []Option{WithBodyHash()}, http.StatusBadRequest},
+		{"max size", AuthorizationCarrier{}, body, body, 64, nil, 0},
+		{"over max size", AuthorizationCarrier{}, "", body, 4, nil, http.StatusRequestEntityTooLarge},
+		{"over max body size", AuthorizationCarrier{}, body, body, 0, []Option{WithMaxBodySize(4)}, http.StatusRequestEntityTooLarge},
+		{"max size under max body size", AuthorizationCarrier{}, "", body, 4, []Option{WithMaxBodySize(64)}, http.StatusRequestEntityTooLarge},
+		{"unbounded", AuthorizationCarrier{}, body, body, 0, []Option{WithMaxBodySize(0)}, 0},
+	}
+	for _, tt := range tests {
+		v := NewValidator(func(key string) (string, int) { return "secret", 300 }, tt.opts...)
+		r := httptest.NewRequest(http.MethodPost, "http://example.com/payments", strings.NewReader(tt.sent))
+		var opts []SignOption
+		if tt.signed != "" {
+			opts = append(opts, SignBody([]byte(tt.signed)))
+		}
+		if tt.max > 0 {
+			opts = append(opts, SignMaxSize(tt.max))
+		}
+		SignRequest(r, "key", "secret", tt.carrier, opts...)
+		if _, err := v.Validate(r); status(err) != tt.status {
+			t.Errorf("%s: status %d, want %d (%v)", tt.name, status(err), tt.status, err)
+			continue
+		} else if err != nil {
+			continue
+		}
+		// The body is left to be read after validation.
+		if b, err := io.ReadAll(r.Body); err != nil || string(b) != tt.sent {
+			t.Errorf("%s: body %q, %v, want %q", tt.name, b, err, tt.sent)
+		}
+	}
+}
+
+func TestMaxSizeBody(t *testing.T) {
+	v := NewValidator(func(key string) (string, int) { return "secret", 300 })
+	r := httptest.NewRequest(http.MethodPut, "http://example.com/upload", strings.NewReader("too large"))
+	r.ContentLength = -1
+	SignRequest(r, "key", "secret", AuthorizationCarrier{}, SignMaxSize(4))
+	if _, err := v.Validate(r); err != nil {
+		t.Fatal(err)
+	}
+	// Bodies without a length are cut off at the max size.
+	if _, err := io.ReadAll(r.Body); err == nil {
+		t.Error("read past the max size")
+	}
+}
+
+func TestMaxBodySizeHash(t *testing.T) {
+	const body = "too large"
+	v := NewValidator(func(key string) (string, int) { return "secret", 300 }, WithMaxBodySize(4))
+	r := httptest.NewRequest(http.MethodPut, "http://example.com/upload", strings.NewReader(body))
+	r.ContentLength = -1
+	SignRequest(r, "key", "secret", AuthorizationCarrier{}, SignBody([]byte(body)))
+	// Digests are read only up to the max body size, signed or not.
+	if _, err := v.Validate(r); status(err) != http.StatusBadRequest {
+		t.Errorf("status %d, want %d (%v)", status(err), http.StatusBadRequest, err)
+	}
+}
@@ -1,3 +1,5 @@
 #!/bin/sh -
 
-go build ../hancock ../hancock/wrappers
+go build ../hancock ../hancock/blake2 ../hancock/chaos ../hancock/compat ../hancock/gateway ../hancock/hancocktest ../hancock/opa ../hancock/redis ../hancock/wrappers
+go build ../hancock/cmd/hancock-demo ../hancock/cmd/hancock-difffuzz
+GOOS=js GOARCH=wasm go build -o hancock.wasm ../hancock/cmd/hancock-wasm
@@ -0,0 +1,50 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CacheKey returns a cache key for r with the signing parameters stripped,
+// so every signed URL for the same resource shares one cache entry.
+// The remaining parameters are sorted by key.
+func (v *Validator) CacheKey(r *http.Request) string {
+	q := r.URL.Query()
+	v.params.strip(q)
+	key := requestHost(r) + r.URL.Path
+	if enc := q.Encode(); enc != "" {
+		key += "?" + enc
+	}
+	return key
+}
+
+// SetCacheHeaders marks a validated response as cacheable for maxAge.
+// Shared allows caching by CDNs and proxies, which must then key their cache
+// with CacheKey and still validate each request themselves; a shared cache
+// that strips the signature without validating serves content to anyone.
+//
+// Headers that are part of the signature are added to Vary.
+func (v *Validator) SetCacheHeaders(w http.ResponseWriter, maxAge time.Duration, shared bool) {
+	scope := "private"
+	if shared {
+		scope = "public"
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int(maxAge.Seconds())))
+	if v.idem != nil {
+		w.Header().Add("Vary", IdempotencyKeyHeader)
+	}
+}
+
+// CacheHandler returns a handler that validates requests, setting cache
+// headers (see SetCacheHeaders) on validated responses before invoking h.
+func (v *Validator) CacheHandler(maxAge time.Duration, shared bool, h http.Handler) http.Handler {
+	return v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v.SetCacheHeaders(w, maxAge, shared)
+		h.ServeHTTP(w, r)
+	}))
+}
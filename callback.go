@@ -0,0 +1,48 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"time"
+)
+
+// SignCallback returns a URL for a third-party processor (e.g. a
+// transcoder) to PUT its result to, only to urlStr's path, of at most
+// maxSize bytes (see SignMaxSize), within ttl. Add SignNonce for validators
+// recording nonces, so it can be used once. It's signed with Version4, which
+// signs the path.
+func SignCallback(key, pKey, urlStr string, maxSize int64, ttl time.Duration, opts ...SignOption) string {
+	opts = append([]SignOption{SignVersion(Version4), SignExpires(ttl), SignMaxSize(maxSize)}, opts...)
+	return Sign(http.MethodPut, key, pKey, urlStr, nil, opts...)
+}
+
+// CallbackHandler returns a handler that validates callback uploads (see
+// SignCallback) before calling h, rejecting other methods and URLs not
+// signed as callbacks, or with a version not signing their path.
+func (v *Validator) CallbackHandler(h http.Handler) http.Handler {
+	h = v.Handler(h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			v.writeError(w, v.newError(http.StatusMethodNotAllowed, r, "callback method %s", r.Method))
+			return
+		}
+		// Carriers may carry the max size, extraction errors and limits are
+		// left to h.
+		if er, err := v.carry(r); err == nil {
+			q := er.URL.Query()
+			if !q.Has(v.params.MaxSize) {
+				v.writeError(w, v.newError(http.StatusBadRequest, r, "not a callback URL"))
+				return
+			}
+			// Unknown versions are left to h to reject.
+			if f, ok := formatFor(q.Get(v.params.Version)); ok && !f.path {
+				v.writeError(w, v.newError(http.StatusBadRequest, r, "callback URL not signed for its path"))
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallbackHandler(t *testing.T) {
+	v := NewValidator(func(key string) (string, int) {
+		if key == "key" {
+			return "secret", 300
+		}
+		return "", 0
+	})
+	h := v.CallbackHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	callback := SignCallback("key", "secret", "http://example.com/jobs/1", 4, time.Minute)
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		status int
+	}{
+		{"valid", http.MethodPut, callback, "done", http.StatusOK},
+		{"other path", http.MethodPut, strings.Replace(callback, "/jobs/1", "/jobs/2", 1), "done", http.StatusUnauthorized},
+		{"other method", http.MethodPost, callback, "done", http.StatusMethodNotAllowed},
+		{"too large", http.MethodPut, callback, "too large", http.StatusRequestEntityTooLarge},
+		{"not a callback", http.MethodPut, Sign(http.MethodPut, "key", "secret", "http://example.com/jobs/1", nil, SignVersion(Version4)), "done", http.StatusBadRequest},
+		{"path not signed", http.MethodPut, SignCallback("key", "secret", "http://example.com/jobs/1", 4, time.Minute, SignVersion(Version3)), "done", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// CanonicalRequest holds what may be signed of a request.
+type CanonicalRequest struct {
+	Method string
+	// Host is set when the host is bound (see WithHostBinding).
+	Host string
+	// Path is escaped, as sent, and normalized (see WithPathNormalization).
+	Path string
+	// Query holds the signing parameters, but the signature.
+	Query          url.Values
+	IdempotencyKey string
+	// Header is the request's, nil when signing a bare query string
+	// (see SignQS).
+	Header http.Header
+}
+
+// Canonicalizer returns the string signed for a request, e.g. to sign
+// headers, reusing hancock's HMACs, keys and middleware. The canonical
+// string versions (see Version1) are Canonicalizers.
+type Canonicalizer interface {
+	Canonical(req *CanonicalRequest) string
+}
+
+// VersionCanonicalizer returns the Canonicalizer of a canonical string
+// version, "" being Version1, e.g. for Canonicalizers extending it.
+func VersionCanonicalizer(version string) (Canonicalizer, bool) {
+	f, ok := formatFor(version)
+	if !ok {
+		return nil, false
+	}
+	return f, true
+}
+
+// CanonicalizerFunc is a function Canonicalizer.
+type CanonicalizerFunc func(req *CanonicalRequest) string
+
+// Canonical calls fn.
+func (fn CanonicalizerFunc) Canonical(req *CanonicalRequest) string {
+	return fn(req)
+}
+
+// WithCanonicalizer validates the string returned by c, rather than by the
+// request's canonical string version, which is still checked against those
+// accepted. Clients must sign with the same Canonicalizer (see
+// SignCanonicalizer).
+func WithCanonicalizer(c Canonicalizer) Option {
+	return func(v *Validator) {
+		v.canonicalizer = c
+	}
+}
+
+// SignCanonicalizer signs the string returned by c (see WithCanonicalizer).
+func SignCanonicalizer(c Canonicalizer) SignOption {
+	return func(cfg *signConfig) {
+		cfg.canonicalizer = c
+	}
+}
+
+// signHeader sets the headers signed by a Canonicalizer.
+func signHeader(h http.Header) SignOption {
+	return func(c *signConfig) {
+		c.header = h
+	}
+}
+
+// canonicalizer returns c when set, f otherwise.
+func canonicalizer(c Canonicalizer, f *format) Canonicalizer {
+	if c != nil {
+		return c
+	}
+	return f
+}
@@ -0,0 +1,443 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Carrier attaches the signing parameters to requests other than in the
+// query string, e.g. in headers or cookies. What's signed is the same
+// whichever carrier is used, so new transports need no change to
+// validation.
+type Carrier interface {
+	// Extract returns the signing parameters, of the given names, carried
+	// by r, or nil when it carries none.
+	Extract(r *http.Request, names []string) (url.Values, error)
+	// Inject attaches the signing parameters to r.
+	Inject(r *http.Request, signing url.Values)
+}
+
+// paramCarrier is implemented by carriers using query parameters other
+// than the signing ones, removed before validation.
+type paramCarrier interface {
+	carriedParams() []string
+}
+
+// ErrCarrier is returned for malformed signing parameters.
+var ErrCarrier = errors.New("hancock: malformed signing parameters")
+
+// WithCarrier reads the signing parameters from c, instead of the query
+// string. Query string parameters carried by c are ignored, the others
+// (e.g. all when c carries none) are read from the query string.
+//
+// Without a carrier they're read from the Authorization header, when it
+// uses the Hancock scheme (see AuthorizationCarrier), or else the query.
+func WithCarrier(c Carrier) Option {
+	return func(v *Validator) {
+		v.carrier = c
+	}
+}
+
+// extract returns r with the signing parameters carried by v.carrier moved
+// into its query string, where validation expects them, once its URL is
+// checked against v's limits.
+func (v *Validator) extract(r *http.Request) (*http.Request, *Error) {
+	if err := v.checkLimits(r); err != nil {
+		v.stats.failed.Add(1)
+		return nil, err
+	}
+	return v.carry(r)
+}
+
+// carry is extract without the limits check.
+func (v *Validator) carry(r *http.Request) (*http.Request, *Error) {
+	c := v.carrier
+	if c == nil {
+		c = AuthorizationCarrier{Params: v.params}
+	}
+	names := v.params.names()
+	signing, err := c.Extract(r, names)
+	if err != nil {
+		return nil, v.newError(http.StatusBadRequest, r, "%s", err)
+	}
+	if signing == nil {
+		return r, nil
+	}
+	if err := v.checkCarried(r, signing); err != nil {
+		return nil, err
+	}
+	carried := make([]string, 0, len(signing))
+	for n := range signing {
+		carried = append(carried, n)
+	}
+	if pc, ok := c.(paramCarrier); ok {
+		carried = append(carried, pc.carriedParams()...)
+	}
+	u := *r.URL
+	u.RawQuery = StripParams(u.RawQuery, carried...)
+	if enc := signing.Encode(); enc != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += enc
+	}
+	r2 := *r
+	r2.URL = &u
+	return &r2, nil
+}
+
+// keepBody passes on to r the body of er, extracted from it, which
+// validation may have replaced, e.g. for the body to be read again, or to
+// cap its size. Without it r would be left with a drained body.
+func keepBody(r, er *http.Request) {
+	r.Body = er.Body
+}
+
+// SignRequest signs r, its query string and path, with the signing
+// parameters attached by c, nil for the query string.
+func SignRequest(r *http.Request, key, pKey string, c Carrier, opts ...SignOption) {
+	cfg := newSignConfig(opts)
+	opts = append([]SignOption{SignPath(r.URL.EscapedPath()), signHeader(r.Header)}, opts...)
+	signed, _ := url.ParseQuery(SignQS(r.Method, key, pKey, r.URL.Query(), opts...))
+	if c == nil {
+		r.URL.RawQuery = signed.Encode()
+		return
+	}
+	signing := make(url.Values)
+	for _, n := range cfg.params.names() {
+		if vs, ok := signed[n]; ok {
+			signing[n] = vs
+			delete(signed, n)
+		}
+	}
+	r.URL.RawQuery = signed.Encode()
+	c.Inject(r, signing)
+}
+
+// HeaderCarrier carries each signing parameter in its own header, named
+// by Prefix and the parameter, e.g. "X-Hancock-Apikey".
+type HeaderCarrier struct {
+	Prefix string
+}
+
+// Extract returns the signing parameters from r's headers.
+func (c HeaderCarrier) Extract(r *http.Request, names []string) (url.Values, error) {
+	var signing url.Values
+	for _, n := range names {
+		if vs := r.Header.Values(c.Prefix + n); len(vs) > 0 {
+			if signing == nil {
+				signing = make(url.Values)
+			}
+			signing[n] = vs
+		}
+	}
+	return signing, nil
+}
+
+// Inject sets the signing parameters as r's headers.
+func (c HeaderCarrier) Inject(r *http.Request, signing url.Values) {
+	for n, vs := range signing {
+		r.Header.Del(c.Prefix + n)
+		for _, s := range vs {
+			r.Header.Add(c.Prefix+n, s)
+		}
+	}
+}
+
+// CookieCarrier carries the signing parameters, encoded as a query string,
+// in the cookie Name. Browsers send it without scripts adding headers.
+type CookieCarrier struct {
+	Name string
+}
+
+// Extract returns the signing parameters from r's cookie.
+func (c CookieCarrier) Extract(r *http.Request, names []string) (url.Values, error) {
+	cookie, err := r.Cookie(c.Name)
+	if err != nil {
+		return nil, nil
+	}
+	q, err := url.ParseQuery(cookie.Value)
+	if err != nil {
+		return nil, ErrCarrier
+	}
+	return only(q, names), nil
+}
+
+// Inject adds the signing parameters as r's cookie.
+func (c CookieCarrier) Inject(r *http.Request, signing url.Values) {
+	r.AddCookie(&http.Cookie{Name: c.Name, Value: signing.Encode()})
+}
+
+// StructuredCarrier carries the signing parameters in the single Header as
+// comma separated `name=value` pairs, e.g.
+//
+//	X-Hancock: apikey=k, ts=1400000000, data=...
+type StructuredCarrier struct {
+	Header string
+}
+
+// Extract returns the signing parameters from r's header.
+func (c StructuredCarrier) Extract(r *http.Request, names []string) (url.Values, error) {
+	hs := r.Header.Values(c.Header)
+	if len(hs) == 0 {
+		return nil, nil
+	}
+	signing := make(url.Values)
+	for _, h := range hs {
+		for _, pair := range strings.Split(h, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			k, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, ErrCarrier
+			}
+			uv, err := url.QueryUnescape(val)
+			if err != nil {
+				return nil, ErrCarrier
+			}
+			signing.Add(k, uv)
+		}
+	}
+	return only(signing, names), nil
+}
+
+// Inject sets the signing parameters as r's header.
+func (c StructuredCarrier) Inject(r *http.Request, signing url.Values) {
+	names := make([]string, 0, len(signing))
+	for n := range signing {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, n := range names {
+		for _, s := range signing[n] {
+			pairs = append(pairs, n+"="+url.QueryEscape(s))
+		}
+	}
+	r.Header.Set(c.Header, strings.Join(pairs, ", "))
+}
+
+// only returns the values of q with the given names.
+func only(q url.Values, names []string) url.Values {
+	o := make(url.Values)
+	for _, n := range names {
+		if vs, ok := q[n]; ok {
+			o[n] = vs
+		}
+	}
+	return o
+}
+
+// AuthorizationScheme is the Authorization header scheme of signed requests.
+const AuthorizationScheme = "Hancock"
+
+// AuthorizationCarrier carries the signing parameters in the Authorization
+// header, for clients that can't add query parameters, e.g.
+//
+//	Authorization: Hancock keyId="k", ts="1400000000", sig="..."
+//
+// The API key, timestamp and signature are named keyId, ts and sig, the
+// other parameters by their Params name.
+type AuthorizationCarrier struct {
+	// Params names the signing parameters, unset names use DefaultParams.
+	Params Params
+}
+
+// authName returns the Authorization header name of the parameter n.
+func (c AuthorizationCarrier) authName(n string) string {
+	p := c.Params.withDefaults()
+	switch n {
+	case p.APIKey:
+		return "keyId"
+	case p.Timestamp:
+		return "ts"
+	case p.Signature:
+		return "sig"
+	}
+	return n
+}
+
+// Extract returns the signing parameters from r's Authorization header.
+func (c AuthorizationCarrier) Extract(r *http.Request, names []string) (url.Values, error) {
+	scheme, params, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, AuthorizationScheme) {
+		return nil, nil
+	}
+	byAuth := make(map[string]string, len(names))
+	for _, n := range names {
+		byAuth[c.authName(n)] = n
+	}
+	signing := make(url.Values)
+	for _, pair := range strings.Split(params, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, ErrCarrier
+		}
+		if uq, err := strconv.Unquote(val); err == nil {
+			val = uq
+		}
+		if n, ok := byAuth[k]; ok {
+			signing.Add(n, val)
+		}
+	}
+	return signing, nil
+}
+
+// Inject sets the signing parameters as r's Authorization header.
+func (c AuthorizationCarrier) Inject(r *http.Request, signing url.Values) {
+	p := c.Params.withDefaults()
+	var pairs []string
+	for _, n := range p.names() {
+		for _, s := range signing[n] {
+			pairs = append(pairs, c.authName(n)+"="+strconv.Quote(s))
+		}
+	}
+	r.Header.Set("Authorization", AuthorizationScheme+" "+strings.Join(pairs, ", "))
+}
+
+// SignatureHeaderCarrier carries only the signature, in Header (by default
+// X-Hancock-Signature), the other signing parameters staying in the query
+// string. For CDNs and WAFs dropping or normalizing unknown parameters, with
+// a version normalizing the query (see Version3).
+type SignatureHeaderCarrier struct {
+	Header string
+	// Params names the signing parameters, unset names use DefaultParams.
+	Params Params
+}
+
+func (c SignatureHeaderCarrier) header() string {
+	if c.Header == "" {
+		return WebhookSignatureHeader
+	}
+	return c.Header
+}
+
+// Extract returns the signature from r's header.
+func (c SignatureHeaderCarrier) Extract(r *http.Request, names []string) (url.Values, error) {
+	vs := r.Header.Values(c.header())
+	if len(vs) == 0 {
+		return nil, nil
+	}
+	return url.Values{c.Params.withDefaults().Signature: vs}, nil
+}
+
+// Inject sets the signature as r's header, and the other signing
+// parameters in its query string.
+func (c SignatureHeaderCarrier) Inject(r *http.Request, signing url.Values) {
+	sig := c.Params.withDefaults().Signature
+	q := r.URL.Query()
+	for n, vs := range signing {
+		if n != sig {
+			q[n] = vs
+		}
+	}
+	r.URL.RawQuery = q.Encode()
+	r.Header.Del(c.header())
+	for _, s := range signing[sig] {
+		r.Header.Add(c.header(), s)
+	}
+}
+
+// SignAuthorization signs r with the signing parameters in its
+// Authorization header (see AuthorizationCarrier).
+func SignAuthorization(r *http.Request, key, pKey string, opts ...SignOption) {
+	SignRequest(r, key, pKey, AuthorizationCarrier{Params: newSignConfig(opts).params}, opts...)
+}
+
+// headerRedactor is implemented by carriers carrying the signing
+// parameters in headers, redacted from h, a copy, by redactHeader. p names
+// the signing parameters of the validator.
+type headerRedactor interface {
+	redactHeader(h http.Header, p Params)
+}
+
+// redactSigning replaces the signing parameters in signing, but for the
+// timestamp, by Redacted, and the API keys by their KeyAlias.
+func redactSigning(signing url.Values, p Params) {
+	for n, vs := range signing {
+		for i, s := range vs {
+			switch n {
+			case p.APIKey:
+				vs[i] = KeyAlias(s)
+			case p.Timestamp:
+			default:
+				vs[i] = Redacted
+			}
+		}
+	}
+}
+
+func (c HeaderCarrier) redactHeader(h http.Header, p Params) {
+	signing, _ := c.Extract(&http.Request{Header: h}, p.names())
+	redactSigning(signing, p)
+	c.Inject(&http.Request{Header: h}, signing)
+}
+
+func (c CookieCarrier) redactHeader(h http.Header, p Params) {
+	lines := h.Values("Cookie")
+	for i, line := range lines {
+		pairs := strings.Split(line, ";")
+		for j, pair := range pairs {
+			name, val, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if name != c.Name {
+				continue
+			}
+			redacted := Redacted
+			if q, err := url.ParseQuery(val); err == nil {
+				redactSigning(q, p)
+				redacted = q.Encode()
+			}
+			pairs[j] = " " + name + "=" + redacted
+		}
+		lines[i] = strings.TrimSpace(strings.Join(pairs, ";"))
+	}
+}
+
+func (c StructuredCarrier) redactHeader(h http.Header, p Params) {
+	if len(h.Values(c.Header)) == 0 {
+		return
+	}
+	signing, err := c.Extract(&http.Request{Header: h}, p.names())
+	if err != nil {
+		h.Set(c.Header, Redacted)
+		return
+	}
+	redactSigning(signing, p)
+	c.Inject(&http.Request{Header: h}, signing)
+}
+
+func (c SignatureHeaderCarrier) redactHeader(h http.Header, p Params) {
+	vs := h.Values(c.header())
+	for i := range vs {
+		vs[i] = Redacted
+	}
+}
+
+// redactedHeader returns r's header with the credentials of the
+// Authorization header, and the signing parameters carried by v.carrier,
+// left out, for errors and logs.
+func (v *Validator) redactedHeader(r *http.Request) http.Header {
+	h := r.Header.Clone()
+	if a := h.Get("Authorization"); a != "" {
+		scheme, _, _ := strings.Cut(a, " ")
+		h.Set("Authorization", scheme+" [redacted]")
+	}
+	if hr, ok := v.carrier.(headerRedactor); ok {
+		hr.redactHeader(h, v.params)
+	}
+	return h
+}
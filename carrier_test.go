@@ -0,0 +1,46 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorHeaderRedaction(t *testing.T) {
+	const apikey, secret = "the-key", "secret"
+	tests := []struct {
+		name    string
+		carrier Carrier
+	}{
+		{"authorization", nil},
+		{"headers", HeaderCarrier{Prefix: "X-Hancock-"}},
+		{"cookie", CookieCarrier{Name: "hancock"}},
+		{"structured", StructuredCarrier{Header: "X-Hancock"}},
+		{"signature header", SignatureHeaderCarrier{}},
+		{"token header", TokenCarrier{Header: "X-Hancock-Token"}},
+	}
+	for _, tt := range tests {
+		v := NewValidator(nil, WithCarrier(tt.carrier), WithKeyInfo(keyMap(nil)))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		c := tt.carrier
+		if c == nil {
+			c = AuthorizationCarrier{}
+		}
+		SignRequest(r, apikey, secret, c, SignNonce())
+		carried, _ := v.carry(r)
+		header, _ := v.newError(http.StatusUnauthorized, r, "unauthorized").Request.Header.(string)
+		if strings.Contains(header, apikey) {
+			t.Errorf("%s: API key in %s", tt.name, header)
+		}
+		for _, sig := range carried.URL.Query()[DefaultParams.Signature] {
+			if strings.Contains(header, sig) {
+				t.Errorf("%s: signature in %s", tt.name, header)
+			}
+		}
+	}
+}
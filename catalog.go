@@ -0,0 +1,117 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Catalog is a signed set of asset paths, valid until Expires.
+// A path ending in "/" covers every path beneath it.
+//
+// A single catalog token, passed as the "catalog" query parameter, replaces
+// signing each asset URL individually.
+type Catalog struct {
+	APIKey  string   `json:"apikey"`
+	Expires int64    `json:"expires"`
+	Paths   []string `json:"paths"`
+	Data    string   `json:"data"`
+}
+
+// SignCatalog returns an encoded catalog token for paths, valid until expires.
+func SignCatalog(key, pKey string, paths []string, expires time.Time) string {
+	c := &Catalog{
+		APIKey:  key,
+		Expires: expires.UTC().Unix(),
+		Paths:   append([]string(nil), paths...),
+	}
+	sort.Strings(c.Paths)
+	c.Data = c.signature(pKey)
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCatalog decodes a catalog token without verifying it.
+func DecodeCatalog(token string) (*Catalog, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Catalog)
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	if !sort.StringsAreSorted(c.Paths) {
+		return nil, fmt.Errorf("unsorted catalog paths")
+	}
+	return c, nil
+}
+
+// Contains reports whether path is covered by the catalog.
+func (c *Catalog) Contains(path string) bool {
+	if c.has(path) {
+		return true
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && c.has(path[:i+1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Catalog) has(p string) bool {
+	i := sort.SearchStrings(c.Paths, p)
+	return i < len(c.Paths) && c.Paths[i] == p
+}
+
+func (c *Catalog) signature(pKey string) string {
+	hash := hmac.New(sha256.New, []byte(pKey))
+	fmt.Fprintf(hash, "catalog:%s:%d", c.APIKey, c.Expires)
+	for _, p := range c.Paths {
+		fmt.Fprintf(hash, "\n%s", p)
+	}
+	return base64.URLEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// ValidateCatalog checks that the request path is covered by the request's
+// "catalog" token, and that the token is signed and unexpired.
+func ValidateCatalog(r *http.Request, keyFn KeyFunc) *Error {
+	return NewValidator(keyFn).ValidateCatalog(r)
+}
+
+// ValidateCatalog is ValidateCatalog with v's keys and clock.
+func (v *Validator) ValidateCatalog(r *http.Request) *Error {
+	c, err := DecodeCatalog(r.URL.Query().Get("catalog"))
+	if err != nil {
+		return v.newError(http.StatusBadRequest, r, "invalid catalog: %s", err)
+	}
+	var info *KeyInfo
+	if v.keys != nil {
+		if info, err = v.keyInfo(c.APIKey); err != nil {
+			return v.newError(http.StatusServiceUnavailable, r, "key lookup failed: %s", err)
+		}
+	}
+	if info == nil || info.Secret == "" {
+		return v.newError(http.StatusUnauthorized, r, "unknown catalog key")
+	}
+	if !hmac.Equal([]byte(c.signature(info.Secret)), []byte(c.Data)) {
+		return v.newError(http.StatusUnauthorized, r, "catalog signature mismatch")
+	}
+	if v.clock.Now().UTC().Unix() > c.Expires {
+		return v.newError(http.StatusNotAcceptable, r, "expired catalog %d", c.Expires)
+	}
+	if r.URL.Path == "" || !c.Contains(r.URL.Path) {
+		return v.newError(http.StatusForbidden, r, "path not in catalog")
+	}
+	return nil
+}
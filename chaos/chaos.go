@@ -0,0 +1,62 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package chaos injects faults into hancock validation on a share of
+// requests, so teams can verify their clients retry and re-sign correctly
+// before a production incident does.
+//
+// It's meant for test environments only.
+package chaos
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"code.minty.io/hancock"
+)
+
+// hit reports whether a request falls within percent (0-100) of requests.
+func hit(percent float64) bool {
+	return rand.Float64()*100 < percent
+}
+
+// Keys delays percent of the lookups made through fn by latency.
+func Keys(fn hancock.KeyInfoFunc, percent float64, latency time.Duration) hancock.KeyInfoFunc {
+	return func(key string) (*hancock.KeyInfo, error) {
+		if hit(percent) {
+			time.Sleep(latency)
+		}
+		return fn(key)
+	}
+}
+
+// Failures returns middleware that fails percent of requests with status
+// before they reach the wrapped handler (typically a Validator's Handler).
+func Failures(percent float64, status int) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hit(percent) {
+				w.WriteHeader(status)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Clock is a hancock.Clock that jumps by Jump on Percent of readings,
+// simulating skew between the clients' and the validator's clocks.
+type Clock struct {
+	Percent float64
+	Jump    time.Duration
+}
+
+// Now returns the time, jumped on a share of readings.
+func (c Clock) Now() time.Time {
+	if hit(c.Percent) {
+		return time.Now().Add(c.Jump)
+	}
+	return time.Now()
+}
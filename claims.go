@@ -0,0 +1,85 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Claims are values signed along with a request (see SignClaims), e.g.
+// "scope" or "uid", that handlers can authorize with knowing clients
+// couldn't have changed them.
+type Claims map[string]string
+
+const claimsKey contextKey = 1
+
+// SignClaims signs claims along with the request, kept apart from its
+// query values.
+func SignClaims(claims Claims) SignOption {
+	return func(c *signConfig) {
+		c.claims = claims
+	}
+}
+
+// encode returns c as the claims parameter's value.
+func (c Claims) encode() string {
+	q := make(url.Values, len(c))
+	for k, v := range c {
+		q.Set(k, v)
+	}
+	return q.Encode()
+}
+
+// ValidateClaims validates r (see Validate), also returning its claims.
+func (v *Validator) ValidateClaims(r *http.Request) (url.Values, Claims, *Error) {
+	er, err := v.extract(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	q, _, err := v.validateKey(er)
+	keepBody(r, er)
+	if err != nil {
+		return nil, nil, err
+	}
+	claims, err := v.claims(er)
+	if err != nil {
+		return nil, nil, err
+	}
+	return q, claims, nil
+}
+
+// ClaimsFromContext returns the claims of a validated request, nil when it
+// has none.
+func ClaimsFromContext(ctx context.Context) Claims {
+	claims, _ := ctx.Value(claimsKey).(Claims)
+	return claims
+}
+
+// withClaims returns a copy of ctx carrying claims, when there are any.
+func withClaims(ctx context.Context, claims Claims) context.Context {
+	if claims == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// claims returns the claims of r, already validated.
+func (v *Validator) claims(r *http.Request) (Claims, *Error) {
+	raw := r.URL.Query().Get(v.params.Claims)
+	if raw == "" {
+		return nil, nil
+	}
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return nil, v.newError(http.StatusBadRequest, r, "malformed claims")
+	}
+	claims := make(Claims, len(q))
+	for k := range q {
+		claims[k] = q.Get(k)
+	}
+	return claims, nil
+}
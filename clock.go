@@ -0,0 +1,50 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import "time"
+
+// Clock tells a Validator, or the other types given one (e.g. with their
+// WithClock method), the time timestamps are set and checked against.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the system's Clock, used by default. Tests set the time
+// with hancocktest.Clock instead.
+type SystemClock struct{}
+
+// Now returns time.Now().
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock checks timestamps against c instead of the system clock.
+func WithClock(c Clock) Option {
+	return func(v *Validator) {
+		v.clock = c
+	}
+}
+
+// SignClock timestamps signatures with c instead of the system clock. The
+// functions minting tokens take it too, timing their expiry with c.
+func SignClock(c Clock) SignOption {
+	return func(cfg *signConfig) {
+		cfg.clock = c
+	}
+}
+
+// signNow returns the time of the clock set by opts.
+func signNow(opts []SignOption) time.Time {
+	return newSignConfig(opts).clock.Now()
+}
+
+// clockOr returns c, or SystemClock when nil.
+func clockOr(c Clock) Clock {
+	if c == nil {
+		return SystemClock{}
+	}
+	return c
+}
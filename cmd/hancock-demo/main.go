@@ -0,0 +1,215 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command hancock-demo serves an interactive signer for developers
+// integrating with hancock: paste a key, secret, method and URL to see the
+// canonical string, the signature and the signed URL, then send it.
+//
+//	hancock-demo [-addr localhost:8080] [-key demo] [-secret demo-secret]
+//
+// Requests to /echo/ are validated with -key and -secret and echo back
+// what the handler sees, to try signing against. Secrets pasted in the page
+// are sent to the demo server, run it locally only.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	"code.minty.io/hancock"
+)
+
+type signRequest struct {
+	Method  string `json:"method"`
+	URL     string `json:"url"`
+	Key     string `json:"key"`
+	Secret  string `json:"secret"`
+	Version string `json:"version"`
+}
+
+type signResponse struct {
+	Canonical string   `json:"canonical"`
+	Signature []string `json:"signature"`
+	URL       string   `json:"url"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// sign signs req, capturing its canonical string.
+func sign(req *signRequest) (*signResponse, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	if req.Key == "" || req.Secret == "" {
+		return nil, fmt.Errorf("missing key or secret")
+	}
+	base, ok := hancock.VersionCanonicalizer(req.Version)
+	if !ok {
+		return nil, fmt.Errorf("unsupported version %q", req.Version)
+	}
+	res := new(signResponse)
+	capture := hancock.CanonicalizerFunc(func(r *hancock.CanonicalRequest) string {
+		res.Canonical = base.Canonical(r)
+		return res.Canonical
+	})
+
+	qs := u.Query()
+	u.RawQuery = ""
+	res.URL = hancock.Sign(req.Method, req.Key, req.Secret, u.String(), qs,
+		hancock.SignVersion(req.Version), hancock.SignCanonicalizer(capture))
+	if signed, err := url.Parse(res.URL); err == nil {
+		res.Signature = signed.Query()[hancock.DefaultParams.Signature]
+	}
+	return res, nil
+}
+
+func signHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	req := new(signRequest)
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(req); err != nil {
+		writeJSON(w, http.StatusBadRequest, &signResponse{Error: err.Error()})
+		return
+	}
+	res, err := sign(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &signResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// echo writes what a handler behind the validator sees of r.
+func echo(w http.ResponseWriter, r *http.Request) {
+	res := map[string]interface{}{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"query":  r.URL.Query(),
+	}
+	if info, ok := hancock.FromContext(r.Context()); ok {
+		res["key"] = info.Key
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "listen address")
+	key := flag.String("key", "demo", "API key accepted by /echo/")
+	secret := flag.String("secret", "demo-secret", "secret of -key")
+	flag.Parse()
+
+	keyFn := func(k string) (string, int) {
+		if k == *key {
+			return *secret, 300
+		}
+		return "", 0
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	v := hancock.NewValidator(keyFn, hancock.WithLog(logger.Println), hancock.WithErrorFormat(hancock.ErrorJSON))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, page, html.EscapeString(*key), html.EscapeString(*secret))
+	})
+	mux.HandleFunc("/sign", signHandler)
+	mux.Handle("/echo/", v.Handler(http.HandlerFunc(echo)))
+
+	logger.Printf("hancock-demo listening on http://%s", *addr)
+	logger.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// page is the signer, formatted with the demo key and secret.
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>hancock signer</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; }
+label { display: block; margin-top: .5em; }
+input, select { width: 100%%; font-family: monospace; }
+pre { background: #f4f4f4; padding: .5em; white-space: pre-wrap; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>hancock signer</h1>
+<form id="form">
+<label>API key <input id="key" value="%s"></label>
+<label>Secret <input id="secret" value="%s"></label>
+<label>Method
+<select id="method"><option>GET</option><option>POST</option><option>PUT</option><option>DELETE</option></select>
+</label>
+<label>URL <input id="url"></label>
+<label>Version
+<select id="version"><option value="">1 (default)</option><option>2</option><option>3</option><option>4</option><option>5</option></select>
+</label>
+<p><button type="submit">Sign</button> <button type="button" id="send" disabled>Send</button></p>
+</form>
+<h2>Canonical string</h2>
+<pre id="canonical"></pre>
+<h2>Signature</h2>
+<pre id="signature"></pre>
+<h2>Signed URL</h2>
+<pre id="signed"></pre>
+<h2>Response</h2>
+<pre id="response"></pre>
+<script>
+const $ = id => document.getElementById(id);
+$("url").value = location.origin + "/echo/hello?name=world";
+let signed = "";
+
+$("form").addEventListener("submit", async e => {
+	e.preventDefault();
+	const res = await fetch("/sign", {
+		method: "POST",
+		body: JSON.stringify({
+			method: $("method").value, url: $("url").value,
+			key: $("key").value, secret: $("secret").value, version: $("version").value,
+		}),
+	});
+	const body = await res.json();
+	if (body.error) {
+		$("canonical").textContent = body.error;
+		$("signature").textContent = $("signed").textContent = "";
+		$("send").disabled = true;
+		return;
+	}
+	signed = body.url;
+	$("canonical").textContent = body.canonical;
+	$("signature").textContent = body.signature.join("\n");
+	$("signed").textContent = signed;
+	$("send").disabled = false;
+});
+
+$("send").addEventListener("click", async () => {
+	try {
+		const res = await fetch(signed, {method: $("method").value});
+		$("response").textContent = res.status + " " + res.statusText + "\n\n" + await res.text();
+	} catch (err) {
+		$("response").textContent = String(err);
+	}
+});
+</script>
+</body>
+</html>
+`
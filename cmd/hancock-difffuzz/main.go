@@ -0,0 +1,222 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command hancock-difffuzz cross-checks another implementation of hancock
+// (e.g. a generated TypeScript or Python client, or the WASM build) against
+// this one on random inputs, catching drift between the two.
+//
+//	hancock-difffuzz [-n 1000] [-seed 0] command [args...]
+//
+// The command speaks JSON lines on stdin and stdout, one response per
+// request, mirroring the globals of cmd/hancock-wasm:
+//
+//	{"op":"sign","method":"GET","key":"k","pKey":"p","query":"a=1","version":"3"}
+//	{"query":"a=1&apikey=k&ts=...&v=3&data=..."}
+//
+//	{"op":"validate","method":"GET","url":"https://example.com/a?...","pKey":"p"}
+//	{"status":0,"error":""}
+//
+// Validation status is 0 for valid requests. Each case checks that requests
+// signed by either implementation are accepted by the other, and that
+// tampered ones are rejected by both. Failing cases are printed, with the
+// seed to reproduce them.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"code.minty.io/hancock"
+)
+
+var versions = []string{"", hancock.Version1, hancock.Version2, hancock.Version3, hancock.Version4, hancock.Version5}
+
+// runes are drawn from when generating inputs, favoring those encodings
+// disagree on.
+var runes = []rune("aZ09-_.~ +%&=;/?#*'!()[]\"\\é€😀\x00\t")
+
+type request struct {
+	Op      string `json:"op"`
+	Method  string `json:"method"`
+	Key     string `json:"key"`
+	PKey    string `json:"pKey"`
+	Query   string `json:"query,omitempty"`
+	Version string `json:"version,omitempty"`
+	URL     string `json:"url,omitempty"`
+}
+
+type response struct {
+	Query  string `json:"query"`
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// impl is the implementation under test.
+type impl struct {
+	enc *json.Encoder
+	dec *json.Decoder
+}
+
+func (m *impl) call(req *request) (*response, error) {
+	if err := m.enc.Encode(req); err != nil {
+		return nil, err
+	}
+	res := new(response)
+	if err := m.dec.Decode(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// testCase is a random signing input.
+type testCase struct {
+	Method  string     `json:"method"`
+	Key     string     `json:"key"`
+	PKey    string     `json:"pKey"`
+	Path    string     `json:"path"`
+	Query   url.Values `json:"query"`
+	Version string     `json:"version"`
+}
+
+func randString(rnd *rand.Rand, max int) string {
+	b := make([]rune, rnd.Intn(max+1))
+	for i := range b {
+		b[i] = runes[rnd.Intn(len(runes))]
+	}
+	return string(b)
+}
+
+func newCase(rnd *rand.Rand) *testCase {
+	c := &testCase{
+		Method:  []string{"GET", "POST", "PUT", "DELETE"}[rnd.Intn(4)],
+		Key:     "key" + randString(rnd, 4),
+		PKey:    "secret" + randString(rnd, 16),
+		Path:    "/" + url.PathEscape(randString(rnd, 8)),
+		Query:   make(url.Values),
+		Version: versions[rnd.Intn(len(versions))],
+	}
+	for i := rnd.Intn(5); i > 0; i-- {
+		name := randString(rnd, 6)
+		// Repeated names and empty values.
+		for j := rnd.Intn(3) + 1; j > 0; j-- {
+			c.Query.Add(name, randString(rnd, 8))
+		}
+	}
+	return c
+}
+
+// tamper changes a signed query string, which must then fail validation.
+func tamper(qs string) string {
+	return qs + "&tampered=1"
+}
+
+// goValidate validates a request with this implementation.
+func goValidate(method, urlStr, pKey string) (int, string) {
+	r, err := http.NewRequest(method, urlStr, nil)
+	if err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+	if _, verr := hancock.Validate(r, pKey, 60); verr != nil {
+		return verr.Status, verr.Message
+	}
+	return 0, ""
+}
+
+// check runs c through both implementations, returning its failures.
+func check(m *impl, c *testCase) ([]string, error) {
+	var fails []string
+	base := "https://example.com"
+
+	// Signed here, validated there.
+	u := hancock.Sign(c.Method, c.Key, c.PKey, base+c.Path, c.Query, hancock.SignVersion(c.Version))
+	res, err := m.call(&request{Op: "validate", Method: c.Method, URL: u, PKey: c.PKey})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status != 0 {
+		fails = append(fails, fmt.Sprintf("rejected Go signature: %d %s", res.Status, res.Error))
+	}
+	if res, err = m.call(&request{Op: "validate", Method: c.Method, URL: tamper(u), PKey: c.PKey}); err != nil {
+		return nil, err
+	}
+	if res.Status == 0 {
+		fails = append(fails, "accepted tampered Go signature")
+	}
+
+	// Signed there, validated here.
+	if res, err = m.call(&request{Op: "sign", Method: c.Method, Key: c.Key, PKey: c.PKey, Query: c.Query.Encode(), Version: c.Version}); err != nil {
+		return nil, err
+	}
+	u = base + "/?" + res.Query
+	if status, msg := goValidate(c.Method, u, c.PKey); status != 0 {
+		fails = append(fails, fmt.Sprintf("signature rejected by Go: %d %s", status, msg))
+	}
+	if status, _ := goValidate(c.Method, tamper(u), c.PKey); status == 0 {
+		fails = append(fails, "tampered signature accepted by Go")
+	}
+	return fails, nil
+}
+
+func main() {
+	n := flag.Int("n", 1000, "number of cases")
+	seed := flag.Int64("seed", 0, "random seed, the time when 0")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: hancock-difffuzz [-n cases] [-seed seed] command [args...]")
+		os.Exit(2)
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	cmd := exec.Command(flag.Arg(0), flag.Args()[1:]...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	m := &impl{enc: json.NewEncoder(in), dec: json.NewDecoder(bufio.NewReader(out))}
+
+	rnd := rand.New(rand.NewSource(*seed))
+	failed := 0
+	for i := 0; i < *n; i++ {
+		c := newCase(rnd)
+		fails, err := check(m, c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "case %d: %s\n", i, err)
+			os.Exit(1)
+		}
+		if len(fails) > 0 {
+			failed++
+			b, _ := json.Marshal(c)
+			fmt.Printf("case %d %s:\n\t%s\n", i, b, strings.Join(fails, "\n\t"))
+		}
+	}
+	in.Close()
+	cmd.Wait()
+
+	fmt.Printf("%d of %d cases failed, seed %d\n", failed, *n, *seed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
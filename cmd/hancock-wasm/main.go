@@ -0,0 +1,67 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build js && wasm
+
+// Command hancock-wasm exposes signing and validation to JavaScript, so edge
+// runtimes (e.g. Cloudflare Workers, nginx njs) and non-Go services share
+// the reference canonicalization.
+//
+//	GOOS=js GOARCH=wasm go build -o hancock.wasm code.minty.io/hancock/cmd/hancock-wasm
+//
+// Once loaded (with Go's wasm_exec.js) it registers the globals:
+//
+//	hancockSignQS(method, key, pKey, query[, version]) string
+//	hancockValidate(method, url, pKey, expireSeconds) {query, status, error}
+//
+// hancockValidate's status is 0 for valid requests.
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"syscall/js"
+
+	"code.minty.io/hancock"
+)
+
+func signQS(this js.Value, args []js.Value) interface{} {
+	if len(args) < 4 {
+		return js.ValueOf(nil)
+	}
+	values, err := url.ParseQuery(args[3].String())
+	if err != nil {
+		return js.ValueOf(nil)
+	}
+	var opts []hancock.SignOption
+	if len(args) > 4 && args[4].Type() == js.TypeString {
+		opts = append(opts, hancock.SignVersion(args[4].String()))
+	}
+	return hancock.SignQS(args[0].String(), args[1].String(), args[2].String(), values, opts...)
+}
+
+func validate(this js.Value, args []js.Value) interface{} {
+	if len(args) < 4 {
+		return result("", http.StatusBadRequest, "missing arguments")
+	}
+	r, err := http.NewRequest(args[0].String(), args[1].String(), nil)
+	if err != nil {
+		return result("", http.StatusBadRequest, err.Error())
+	}
+	q, verr := hancock.Validate(r, args[2].String(), args[3].Int())
+	if verr != nil {
+		return result("", verr.Status, verr.Message)
+	}
+	return result(q.Encode(), 0, "")
+}
+
+func result(query string, status int, msg string) map[string]interface{} {
+	return map[string]interface{}{"query": query, "status": status, "error": msg}
+}
+
+func main() {
+	js.Global().Set("hancockSignQS", js.FuncOf(signQS))
+	js.Global().Set("hancockValidate", js.FuncOf(validate))
+	select {}
+}
@@ -0,0 +1,156 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Command is a one-shot operation, signed by an API key, e.g. issued by
+// operational tooling to a server.
+type Command struct {
+	Key     string          `json:"key"`
+	Action  string          `json:"action"`
+	Args    json.RawMessage `json:"args,omitempty"`
+	Issued  int64           `json:"iat"`
+	Expires int64           `json:"exp"`
+	Nonce   string          `json:"nonce"`
+}
+
+// SignCommand returns a command token for action, with args encoded as
+// JSON, valid once within ttl. opts may set the clock (see SignClock).
+func SignCommand(key, pKey, action string, args interface{}, ttl time.Duration, opts ...SignOption) (string, error) {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	now := signNow(opts)
+	return signToken("command", pKey, &Command{
+		Key:     key,
+		Action:  action,
+		Args:    b,
+		Issued:  now.Unix(),
+		Expires: now.Add(ttl).Unix(),
+		Nonce:   base64.RawURLEncoding.EncodeToString(nonce),
+	}), nil
+}
+
+// ValidateCommand validates a command token, recording its nonce in replay
+// so it's only accepted once.
+func ValidateCommand(token string, keys KeyInfoFunc, replay ReplayStore) (*Command, *KeyInfo, error) {
+	return validateCommand(token, keys, replay, SystemClock{})
+}
+
+func validateCommand(token string, keys KeyInfoFunc, replay ReplayStore, clock Clock) (*Command, *KeyInfo, error) {
+	c := new(Command)
+	if err := decodeToken(token, c); err != nil {
+		return nil, nil, err
+	}
+	info, err := keys(c.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info == nil || info.Secret == "" {
+		return nil, nil, ErrInvalidToken
+	}
+	info = keyed(info, c.Key)
+	if err := verifyToken("command", info.Secret, token); err != nil {
+		return nil, nil, err
+	}
+	now := clock.Now()
+	if now.Unix() > c.Expires {
+		return nil, nil, ErrExpiredToken
+	}
+	if c.Nonce == "" || replay.Seen(c.Key+":"+c.Nonce, now, time.Unix(c.Expires, 0)) {
+		return nil, nil, ErrReplayed
+	}
+	return c, info, nil
+}
+
+// CommandFunc executes a validated command, returning its result. The
+// context carries the signing key's KeyInfo (see FromContext).
+type CommandFunc func(ctx context.Context, c *Command) (interface{}, error)
+
+// Commands executes signed command tokens, POSTed as the request body.
+// Every command run is logged, with the key's alias and the outcome.
+type Commands struct {
+	keys    KeyInfoFunc
+	replay  ReplayStore
+	log     LogFunc
+	clock   Clock
+	mu      sync.RWMutex
+	actions map[string]CommandFunc
+}
+
+// NewCommands returns Commands validating tokens with keys, replay
+// recording their nonces (e.g. a MemoryReplayStore).
+func NewCommands(keys KeyInfoFunc, replay ReplayStore, logFn LogFunc) *Commands {
+	if logFn == nil {
+		logFn = func(...interface{}) {}
+	}
+	return &Commands{keys: keys, replay: replay, log: logFn, clock: SystemClock{}, actions: make(map[string]CommandFunc)}
+}
+
+// WithClock sets the clock tokens' expiration is checked against.
+func (c *Commands) WithClock(clock Clock) *Commands {
+	c.clock = clock
+	return c
+}
+
+// Handle registers fn to execute action.
+func (c *Commands) Handle(action string, fn CommandFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions[action] = fn
+}
+
+// ServeHTTP executes the command token in r's body, writing the command's
+// result as JSON.
+func (c *Commands) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	token, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	cmd, info, err := validateCommand(string(token), c.keys, c.replay, c.clock)
+	if err != nil {
+		c.log("hancock: command rejected:", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	c.mu.RLock()
+	fn, ok := c.actions[cmd.Action]
+	c.mu.RUnlock()
+	if !ok {
+		c.log("hancock: command", cmd.Action, "unknown, key", KeyAlias(cmd.Key), "nonce", cmd.Nonce)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	res, err := fn(NewContext(r.Context(), info), cmd)
+	if err != nil {
+		c.log("hancock: command", cmd.Action, "failed, key", KeyAlias(cmd.Key), "nonce", cmd.Nonce, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.log("hancock: command", cmd.Action, "executed, key", KeyAlias(cmd.Key), "nonce", cmd.Nonce)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,142 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// compactPrefix starts compact tokens, naming their format version.
+const compactPrefix = "hancock.v1."
+
+// CompactToken is the API key, timestamp and signature of a request as a
+// single `hancock.v1.<key>.<ts>.<sig>` token, shorter than separate
+// parameters and easier to log and copy.
+type CompactToken struct {
+	Key       string
+	Timestamp string
+	Signature string
+}
+
+// String returns the encoded token.
+func (t CompactToken) String() string {
+	return compactPrefix + t.Key + "." + t.Timestamp + "." + t.Signature
+}
+
+// ParseCompactToken decodes a token encoded by CompactToken.String. Keys
+// may contain dots, timestamps and signatures can't.
+func ParseCompactToken(s string) (CompactToken, error) {
+	rest, ok := strings.CutPrefix(s, compactPrefix)
+	if !ok {
+		return CompactToken{}, fmt.Errorf("%w: unknown token format", ErrCarrier)
+	}
+	i := strings.LastIndexByte(rest, '.')
+	j := strings.LastIndexByte(rest[:max(i, 0)], '.')
+	if j <= 0 || i == len(rest)-1 || i-j == 1 {
+		return CompactToken{}, fmt.Errorf("%w: malformed token", ErrCarrier)
+	}
+	return CompactToken{Key: rest[:j], Timestamp: rest[j+1 : i], Signature: rest[i+1:]}, nil
+}
+
+// DefaultTokenParam is the query parameter of compact tokens, unless
+// configured otherwise.
+const DefaultTokenParam = "token"
+
+// TokenCarrier carries the API key, timestamp and signature as compact
+// tokens (see CompactToken), in Header when set or else the query
+// parameter Param (by default DefaultTokenParam). The other signing
+// parameters stay in the query string. Requests signed with several
+// secrets carry a token per signature.
+type TokenCarrier struct {
+	Param  string
+	Header string
+	// Params names the signing parameters, unset names use DefaultParams.
+	Params Params
+}
+
+func (c TokenCarrier) param() string {
+	if c.Param == "" {
+		return DefaultTokenParam
+	}
+	return c.Param
+}
+
+// carriedParams returns the query parameters c carries the signing
+// parameters in.
+func (c TokenCarrier) carriedParams() []string {
+	if c.Header != "" {
+		return nil
+	}
+	return []string{c.param()}
+}
+
+// Extract returns the signing parameters of r's tokens.
+func (c TokenCarrier) Extract(r *http.Request, names []string) (url.Values, error) {
+	var tokens []string
+	if c.Header != "" {
+		tokens = r.Header.Values(c.Header)
+	} else {
+		tokens = r.URL.Query()[c.param()]
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	p := c.Params.withDefaults()
+	signing := make(url.Values)
+	for _, s := range tokens {
+		t, err := ParseCompactToken(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(signing) > 0 && (t.Key != signing.Get(p.APIKey) || t.Timestamp != signing.Get(p.Timestamp)) {
+			return nil, fmt.Errorf("%w: mismatched tokens", ErrCarrier)
+		}
+		signing.Set(p.APIKey, t.Key)
+		signing.Set(p.Timestamp, t.Timestamp)
+		signing.Add(p.Signature, t.Signature)
+	}
+	return signing, nil
+}
+
+// Inject sets r's tokens, and the other signing parameters in its query
+// string.
+func (c TokenCarrier) Inject(r *http.Request, signing url.Values) {
+	p := c.Params.withDefaults()
+	q := r.URL.Query()
+	for n, vs := range signing {
+		if n != p.APIKey && n != p.Timestamp && n != p.Signature {
+			q[n] = vs
+		}
+	}
+	var tokens []string
+	for _, sig := range signing[p.Signature] {
+		tokens = append(tokens, CompactToken{signing.Get(p.APIKey), signing.Get(p.Timestamp), sig}.String())
+	}
+	if c.Header != "" {
+		r.Header.Del(c.Header)
+		for _, t := range tokens {
+			r.Header.Add(c.Header, t)
+		}
+	} else {
+		q[c.param()] = tokens
+	}
+	r.URL.RawQuery = q.Encode()
+}
+
+func (c TokenCarrier) redactHeader(h http.Header, _ Params) {
+	if c.Header == "" {
+		return
+	}
+	vs := h.Values(c.Header)
+	for i, s := range vs {
+		vs[i] = Redacted
+		if t, err := ParseCompactToken(s); err == nil {
+			vs[i] = CompactToken{KeyAlias(t.Key), t.Timestamp, Redacted}.String()
+		}
+	}
+}
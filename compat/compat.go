@@ -0,0 +1,152 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package compat verifies tokens signed by other URL/value signing libraries,
+// so services migrating to hancock can keep accepting legacy tokens during
+// the transition.
+package compat
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.minty.io/hancock"
+)
+
+// Clock is the clock token ages are checked against, by default
+// hancock.SystemClock. Tests set it, e.g. to a hancocktest.Clock.
+var Clock hancock.Clock = hancock.SystemClock{}
+
+var (
+	// ErrInvalid is returned for malformed tokens or mismatched signatures.
+	ErrInvalid = errors.New("compat: invalid signature")
+	// ErrExpired is returned for tokens older than the allowed age.
+	ErrExpired = errors.New("compat: expired signature")
+)
+
+// VerifySecureCookie verifies a value encoded by gorilla/securecookie with
+// the default SHA-256 hash, and returns its serialized (gob or JSON) payload.
+// Encrypted cookies (a non-nil block key) aren't supported.
+//
+// When maxAge is non-zero, values older than maxAge are rejected.
+func VerifySecureCookie(hashKey []byte, name, value string, maxAge time.Duration) ([]byte, error) {
+	return VerifySecureCookieHash(sha256.New, hashKey, name, value, maxAge)
+}
+
+// VerifySecureCookieHash is VerifySecureCookie for cookies using a hash
+// other than SHA-256.
+func VerifySecureCookieHash(h func() hash.Hash, hashKey []byte, name, value string, maxAge time.Duration) ([]byte, error) {
+	b, err := base64.URLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	// "date|value|mac"
+	parts := bytes.SplitN(b, []byte("|"), 3)
+	if len(parts) != 3 {
+		return nil, ErrInvalid
+	}
+	mac := hmac.New(h, hashKey)
+	mac.Write([]byte(name + "|"))
+	mac.Write(b[:len(b)-len(parts[2])-1])
+	if !hmac.Equal(mac.Sum(nil), parts[2]) {
+		return nil, ErrInvalid
+	}
+
+	ts, err := strconv.ParseInt(string(parts[0]), 10, 64)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	if maxAge != 0 && ts < Clock.Now().Add(-maxAge).Unix() {
+		return nil, ErrExpired
+	}
+	payload, err := base64.URLEncoding.DecodeString(string(parts[1]))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return payload, nil
+}
+
+// ItsDangerousSalt is the default salt of itsdangerous' Signer.
+// Its serializers (URLSafeSerializer etc.) default to "itsdangerous".
+const ItsDangerousSalt = "itsdangerous.Signer"
+
+// VerifyItsDangerous verifies a `value.signature` token produced by Python's
+// itsdangerous Signer with its defaults (SHA-1, django-concat key
+// derivation), returning the signed value.
+func VerifyItsDangerous(secret, salt []byte, token string) ([]byte, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return nil, ErrInvalid
+	}
+	value, sig := []byte(token[:i]), token[i+1:]
+
+	key := sha1.New()
+	key.Write(salt)
+	key.Write([]byte("signer"))
+	key.Write(secret)
+	mac := hmac.New(sha1.New, key.Sum(nil))
+	mac.Write(value)
+
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac.Sum(nil), got) {
+		return nil, ErrInvalid
+	}
+	return value, nil
+}
+
+// VerifyItsDangerousTimed verifies a `value.timestamp.signature` token
+// produced by itsdangerous' TimestampSigner, returning the signed value and
+// its signing time. When maxAge is non-zero, older tokens are rejected.
+func VerifyItsDangerousTimed(secret, salt []byte, token string, maxAge time.Duration) ([]byte, time.Time, error) {
+	value, err := VerifyItsDangerous(secret, salt, token)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	i := bytes.LastIndexByte(value, '.')
+	if i < 0 {
+		return nil, time.Time{}, ErrInvalid
+	}
+	b, err := base64.RawURLEncoding.DecodeString(string(value[i+1:]))
+	if err != nil {
+		return nil, time.Time{}, ErrInvalid
+	}
+	ts := time.Unix(new(big.Int).SetBytes(b).Int64(), 0)
+	if age := Clock.Now().Sub(ts); maxAge != 0 && (age > maxAge || age < 0) {
+		return nil, ts, ErrExpired
+	}
+	return value[:i], ts, nil
+}
+
+// ItsDangerousPayload decodes the value of a token produced by one of
+// itsdangerous' URL safe serializers, returning its JSON payload.
+func ItsDangerousPayload(value []byte) ([]byte, error) {
+	compressed := len(value) > 0 && value[0] == '.'
+	if compressed {
+		value = value[1:]
+	}
+	b, err := base64.RawURLEncoding.DecodeString(string(value))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	if !compressed {
+		return b, nil
+	}
+	z, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	defer z.Close()
+	return io.ReadAll(z)
+}
@@ -0,0 +1,77 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Semaphore bounds the number of in-flight requests per API key.
+// Implementations may share their counts between instances.
+type Semaphore interface {
+	// Acquire takes a slot for key, reporting false when none are free.
+	Acquire(key string) bool
+	// Release frees a slot taken by Acquire.
+	Release(key string)
+}
+
+// MemorySemaphore is an in-process Semaphore.
+type MemorySemaphore struct {
+	max int
+
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// NewMemorySemaphore returns a Semaphore allowing max in-flight requests per key.
+func NewMemorySemaphore(max int) *MemorySemaphore {
+	return &MemorySemaphore{max: max, inFlight: make(map[string]int)}
+}
+
+// Acquire takes a slot for key.
+func (s *MemorySemaphore) Acquire(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight[key] >= s.max {
+		return false
+	}
+	s.inFlight[key]++
+	return true
+}
+
+// Release frees a slot for key.
+func (s *MemorySemaphore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := s.inFlight[key]; n > 1 {
+		s.inFlight[key] = n - 1
+	} else {
+		delete(s.inFlight, key)
+	}
+}
+
+// ConcurrencyLimit returns middleware that responds with 429 when the key
+// that signed the request has no free slot in sem.
+// It must be wrapped by a Validator's Handler; unvalidated (exempt)
+// requests aren't limited.
+func ConcurrencyLimit(sem Semaphore) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info, ok := FromContext(r.Context())
+			if !ok {
+				h.ServeHTTP(w, r)
+				return
+			}
+			if !sem.Acquire(info.Key) {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			defer sem.Release(info.Key)
+			h.ServeHTTP(w, r)
+		})
+	}
+}
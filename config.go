@@ -0,0 +1,174 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultSkew is the expiration duration, in seconds, used by NewFromConfig
+// for static keys when Config.Skew isn't set.
+const DefaultSkew = 300
+
+// Config declaratively configures a Validator.
+//
+// It can be loaded from JSON with LoadConfig, or from the environment with
+// LoadConfigEnv. YAML deployments can convert their documents to JSON, the
+// field names are the same.
+type Config struct {
+	// Params names the signing parameters, unset names use DefaultParams.
+	Params Params `json:"params"`
+	// Algorithm is the only signature algorithm accepted, any registered
+	// one (see Algorithms) is accepted when it's empty.
+	Algorithm string `json:"algorithm"`
+	// Skew is the allowed timestamp difference in seconds (see Validate).
+	// When set it overrides the per key expiration durations.
+	Skew int `json:"skew"`
+	// Keys are static API key → private key pairs, used when NewFromConfig
+	// isn't given a KeyFunc.
+	Keys map[string]string `json:"keys"`
+	// Exempt lists paths that skip validation (see WithExemptions).
+	Exempt []string `json:"exempt"`
+	// ErrorFormat is either "status" (the default) or "json".
+	ErrorFormat string `json:"errorFormat"`
+	// Environment names the environment served (see WithEnvironment).
+	Environment string `json:"environment"`
+	// Versions are the canonical string versions accepted, all of them
+	// when empty (see WithVersions).
+	Versions []string `json:"versions"`
+}
+
+// LoadConfig decodes a JSON Config from r.
+func LoadConfig(r io.Reader) (*Config, error) {
+	c := new(Config)
+	if err := json.NewDecoder(r).Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadConfigEnv returns a Config from environment variables named with
+// prefix (e.g. "HANCOCK_"):
+//
+//	APIKEY_PARAM, TS_PARAM, SIGNATURE_PARAM,
+//	KEYHINT_PARAM, VERSION_PARAM,
+//	BODYHASH_PARAM, ALGORITHM_PARAM, NONCE_PARAM,
+//	EXPIRES_PARAM, NBF_PARAM, CLAIMS_PARAM,
+//	MAXSIZE_PARAM, ENCODING_PARAM            parameter names
+//	ALGORITHM                                signature algorithm
+//	SKEW                                     skew in seconds
+//	KEYS                                     comma separated key:pKey pairs
+//	EXEMPT                                   comma separated paths
+//	ERROR_FORMAT                             "status" or "json"
+//	ENVIRONMENT                              environment name
+//	VERSIONS                                 comma separated versions
+func LoadConfigEnv(prefix string) (*Config, error) {
+	env := func(name string) string {
+		return os.Getenv(prefix + name)
+	}
+	c := &Config{
+		Params: Params{
+			APIKey:    env("APIKEY_PARAM"),
+			Timestamp: env("TS_PARAM"),
+			Signature: env("SIGNATURE_PARAM"),
+			KeyHint:   env("KEYHINT_PARAM"),
+			Version:   env("VERSION_PARAM"),
+			BodyHash:  env("BODYHASH_PARAM"),
+			Algorithm: env("ALGORITHM_PARAM"),
+			Nonce:     env("NONCE_PARAM"),
+			Expires:   env("EXPIRES_PARAM"),
+			NotBefore: env("NBF_PARAM"),
+			Claims:    env("CLAIMS_PARAM"),
+			MaxSize:   env("MAXSIZE_PARAM"),
+			Encoding:  env("ENCODING_PARAM"),
+		},
+		Algorithm:   env("ALGORITHM"),
+		ErrorFormat: env("ERROR_FORMAT"),
+		Environment: env("ENVIRONMENT"),
+	}
+	if s := env("SKEW"); s != "" {
+		skew, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %sSKEW: %s", prefix, err)
+		}
+		c.Skew = skew
+	}
+	if s := env("KEYS"); s != "" {
+		c.Keys = make(map[string]string)
+		for _, pair := range strings.Split(s, ",") {
+			key, pKey, ok := strings.Cut(pair, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid %sKEYS pair for `%s`", prefix, KeyAlias(key))
+			}
+			c.Keys[key] = pKey
+		}
+	}
+	if s := env("EXEMPT"); s != "" {
+		c.Exempt = strings.Split(s, ",")
+	}
+	if s := env("VERSIONS"); s != "" {
+		c.Versions = strings.Split(s, ",")
+	}
+	return c, nil
+}
+
+// NewFromConfig returns a Validator configured by c.
+// When keyFn is nil the keys are taken from c.Keys.
+func NewFromConfig(c *Config, keyFn KeyFunc, opts ...Option) (*Validator, error) {
+	var o []Option
+
+	o = append(o, WithParams(c.Params))
+
+	if c.Algorithm != "" {
+		if _, ok := algorithm(c.Algorithm); !ok {
+			return nil, fmt.Errorf("unsupported algorithm `%s`", c.Algorithm)
+		}
+		o = append(o, WithAlgorithms(c.Algorithm))
+	}
+
+	if c.Skew != 0 {
+		o = append(o, WithExpires(c.Skew))
+	}
+	if keyFn == nil {
+		keys, skew := c.Keys, c.Skew
+		if skew == 0 {
+			skew = DefaultSkew
+		}
+		keyFn = func(key string) (string, int) {
+			return keys[key], skew
+		}
+	}
+
+	if len(c.Exempt) > 0 {
+		o = append(o, WithExemptions(c.Exempt...))
+	}
+	if c.Environment != "" {
+		o = append(o, WithEnvironment(c.Environment))
+	}
+	for _, version := range c.Versions {
+		if _, ok := formatFor(version); !ok {
+			return nil, fmt.Errorf("unsupported version `%s`", version)
+		}
+	}
+	if len(c.Versions) > 0 {
+		o = append(o, WithVersions(c.Versions...))
+	}
+
+	switch c.ErrorFormat {
+	case "", "status":
+		o = append(o, WithErrorFormat(ErrorStatus))
+	case "json":
+		o = append(o, WithErrorFormat(ErrorJSON))
+	default:
+		return nil, fmt.Errorf("unsupported error format `%s`", c.ErrorFormat)
+	}
+
+	return NewValidator(keyFn, append(o, opts...)...), nil
+}
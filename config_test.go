@@ -0,0 +1,36 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfigEnv(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want Params
+	}{
+		{map[string]string{"ALGORITHM_PARAM": "sig_alg"}, Params{Algorithm: "sig_alg"}},
+		{map[string]string{"ENCODING_PARAM": "e", "NONCE_PARAM": "n"}, Params{Encoding: "e", Nonce: "n"}},
+		// ALGORITHM is the accepted algorithm, not a parameter name.
+		{map[string]string{"ALGORITHM": SHA512}, Params{}},
+	}
+	for i, tt := range tests {
+		prefix := fmt.Sprintf("TEST%d_", i)
+		for name, val := range tt.env {
+			t.Setenv(prefix+name, val)
+		}
+		c, err := LoadConfigEnv(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c.Params, tt.want) {
+			t.Errorf("%v: params %+v, want %+v", tt.env, c.Params, tt.want)
+		}
+	}
+}
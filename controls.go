@@ -0,0 +1,142 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Mode is how a Validator's Handler treats requests.
+type Mode int32
+
+const (
+	// Enforce rejects requests that fail validation.
+	Enforce Mode = iota
+	// Shadow logs requests that fail validation, but lets them through.
+	Shadow
+	// RejectAll rejects every request with a 503.
+	RejectAll
+)
+
+var modeNames = []string{"enforce", "shadow", "reject-all"}
+
+// String returns the mode's name.
+func (m Mode) String() string {
+	if m < 0 || int(m) >= len(modeNames) {
+		return fmt.Sprintf("Mode(%d)", int32(m))
+	}
+	return modeNames[m]
+}
+
+// ParseMode returns the mode named s.
+func ParseMode(s string) (Mode, error) {
+	for i, name := range modeNames {
+		if s == name {
+			return Mode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode `%s`", s)
+}
+
+// Controls switch a Validator between modes at runtime, globally or per API
+// key, for incident response without redeploys.
+type Controls struct {
+	global atomic.Int32
+
+	mu   sync.RWMutex
+	keys map[string]Mode
+}
+
+// NewControls returns Controls enforcing validation for every key.
+func NewControls() *Controls {
+	return &Controls{keys: make(map[string]Mode)}
+}
+
+// WithControls has Handler consult c for the mode of each request.
+func WithControls(c *Controls) Option {
+	return func(v *Validator) {
+		v.controls = c
+	}
+}
+
+// SetMode sets the mode of keys without their own mode.
+func (c *Controls) SetMode(m Mode) {
+	c.global.Store(int32(m))
+}
+
+// SetKeyMode sets the mode of key, overriding the global mode.
+func (c *Controls) SetKeyMode(key string, m Mode) {
+	c.mu.Lock()
+	c.keys[key] = m
+	c.mu.Unlock()
+}
+
+// ClearKeyMode returns key to the global mode.
+func (c *Controls) ClearKeyMode(key string) {
+	c.mu.Lock()
+	delete(c.keys, key)
+	c.mu.Unlock()
+}
+
+// Mode returns the mode for key.
+func (c *Controls) Mode(key string) Mode {
+	c.mu.RLock()
+	m, ok := c.keys[key]
+	c.mu.RUnlock()
+	if ok {
+		return m
+	}
+	return Mode(c.global.Load())
+}
+
+// Handler returns an admin handler for the controls:
+//
+//	GET                       the global mode, and key modes by key alias
+//	POST ?mode=shadow         set the global mode
+//	POST ?mode=shadow&key=K   set the mode of key K
+//	DELETE ?key=K             return key K to the global mode
+//
+// It must be protected, e.g. by a Validator and RequireScopes.
+func (c *Controls) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		key := q.Get("key")
+		switch r.Method {
+		case "GET":
+		case "POST", "PUT":
+			m, err := ParseMode(q.Get("mode"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if key == "" {
+				c.SetMode(m)
+			} else {
+				c.SetKeyMode(key, m)
+			}
+		case "DELETE":
+			c.ClearKeyMode(key)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := struct {
+			Mode string            `json:"mode"`
+			Keys map[string]string `json:"keys,omitempty"`
+		}{Mode: Mode(c.global.Load()).String(), Keys: make(map[string]string)}
+		c.mu.RLock()
+		for k, m := range c.keys {
+			status.Keys[KeyAlias(k)] = m.String()
+		}
+		c.mu.RUnlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+}
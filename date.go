@@ -0,0 +1,61 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+// DateSecret returns the secret derived with HKDF from pKey for the UTC date
+// of t and, when set, service. Clients may be handed the day's secret rather
+// than pKey, a leaked one signing for that date and service only.
+func DateSecret(pKey string, t time.Time, service string) string {
+	info := "hancock:date:" + t.UTC().Format("20060102")
+	if service != "" {
+		info += ":" + service
+	}
+	b, _ := hkdf.Key(sha256.New, []byte(pKey), nil, info, sha256.Size)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// SignDateScope signs with the secret of the signing date and service (see
+// DateSecret), derived after any epoch secret (see SignEpoch).
+func SignDateScope(service string) SignOption {
+	return func(c *signConfig) {
+		c.dates = true
+		c.service = service
+	}
+}
+
+// WithDateScope validates signatures made with the secrets of the date of
+// the requests' timestamps and service (see SignDateScope), instead of the
+// keys' secrets.
+func WithDateScope(service string) Option {
+	return func(v *Validator) {
+		v.dates = true
+		v.service = service
+	}
+}
+
+// dateSecrets returns secrets derived for the date of ts, the Unix time a
+// request was signed at, or now when it isn't one.
+func (v *Validator) dateSecrets(secrets []string, ts string) []string {
+	if !v.dates {
+		return secrets
+	}
+	t := v.clock.Now()
+	if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+		t = time.Unix(sec, 0)
+	}
+	derived := make([]string, len(secrets))
+	for i, s := range secrets {
+		derived[i] = DateSecret(s, t, v.service)
+	}
+	return derived
+}
@@ -0,0 +1,74 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Deprecation describes a canonical string version clients should move
+// off of.
+type Deprecation struct {
+	Version string
+	// Since is when Version was deprecated, sent as the Deprecation header.
+	Since time.Time
+	// Sunset, when set, is when Version stops being accepted, sent as the
+	// Sunset header. Stopping is left to WithVersions.
+	Sunset time.Time
+	// Link, when set, documents moving off of Version, sent as a Link
+	// header.
+	Link string
+}
+
+// DeprecationFunc is called with the key of each request signed with a
+// deprecated version.
+type DeprecationFunc func(key string, d Deprecation)
+
+// WithDeprecations marks versions as deprecated: requests signed with them
+// are still accepted, Handler adding Deprecation and Sunset headers to
+// their responses, and fn, when not nil, is called for each, so operators
+// can tell which keys to chase.
+func WithDeprecations(fn DeprecationFunc, deprecations ...Deprecation) Option {
+	return func(v *Validator) {
+		v.onDeprecated = fn
+		v.deprecations = make(map[string]Deprecation, len(deprecations))
+		for _, d := range deprecations {
+			if d.Version == "" {
+				d.Version = Version1
+			}
+			v.deprecations[d.Version] = d
+		}
+	}
+}
+
+// deprecation returns the deprecation of r's version, when it has one.
+func (v *Validator) deprecation(r *http.Request) (Deprecation, bool) {
+	if len(v.deprecations) == 0 {
+		return Deprecation{}, false
+	}
+	version := r.URL.Query().Get(v.params.Version)
+	if version == "" {
+		version = Version1
+	}
+	d, ok := v.deprecations[version]
+	return d, ok
+}
+
+// setHeaders adds d's headers to h.
+func (d Deprecation) setHeaders(h http.Header) {
+	if !d.Since.IsZero() {
+		h.Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+	} else {
+		h.Set("Deprecation", "true")
+	}
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		h.Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", d.Link))
+	}
+}
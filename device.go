@@ -0,0 +1,44 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// DeviceProofHeader is the header device-bound keys send their proof in.
+const DeviceProofHeader = "X-Hancock-Device-Proof"
+
+// SignDeviceProof adds the DeviceProofHeader to r, already signed (see
+// Sign), proving it comes from the device holding the private key enrolled
+// for r's API key (see Issuer.Provision). The proof covers r's signature,
+// so can't be moved to another request.
+func SignDeviceProof(r *http.Request, device ed25519.PrivateKey, opts ...SignOption) {
+	c := newSignConfig(opts)
+	proof := ed25519.Sign(device, deviceMessage(r.URL.Query()[c.params.Signature]))
+	r.Header.Set(DeviceProofHeader, base64.RawURLEncoding.EncodeToString(proof))
+}
+
+func deviceMessage(sigs []string) []byte {
+	return []byte("device:" + strings.Join(sigs, ","))
+}
+
+// checkDevice verifies r's device proof, when info is bound to a device.
+func (v *Validator) checkDevice(r *http.Request, info *KeyInfo, sigs []string) *Error {
+	if len(info.Device) == 0 {
+		return nil
+	}
+	proof, err := base64.RawURLEncoding.DecodeString(r.Header.Get(DeviceProofHeader))
+	if err != nil || len(proof) == 0 {
+		return v.newError(http.StatusUnauthorized, r, "missing device proof")
+	}
+	if len(info.Device) != ed25519.PublicKeySize || !ed25519.Verify(info.Device, deviceMessage(sigs), proof) {
+		return v.newError(http.StatusUnauthorized, r, "device proof mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Causes of signature mismatches, counted in Status by WithDiagnostics.
+const (
+	// MismatchOrder is a query signed in another parameter order, e.g. as
+	// sent rather than sorted.
+	MismatchOrder = "order"
+	// MismatchEncoding is a query signed with other escaping or empty
+	// values, e.g. by another canonical string version.
+	MismatchEncoding = "encoding"
+	// MismatchPath is a path left unsigned, or signed unnormalized.
+	MismatchPath = "path"
+	// MismatchHost is a host left unsigned, or another host signed.
+	MismatchHost = "host"
+	// MismatchSkew is a secret derived for another epoch (see SignEpoch),
+	// a clock off by more than an epoch, or for another date (see
+	// SignDateScope).
+	MismatchSkew = "skew"
+	// MismatchUnknown is anything else, e.g. a wrong secret.
+	MismatchUnknown = "unknown"
+)
+
+// WithDiagnostics classifies signature mismatches by re-checking them under
+// alternate canonical strings, counting them by cause in Status and logging
+// it, so "signature mismatch" reports become aggregate data. Each mismatch
+// costs several more HMACs.
+func WithDiagnostics() Option {
+	return func(v *Validator) {
+		v.diagnostics = true
+	}
+}
+
+// diagnose returns the cause of a mismatch of r, whose canonical request
+// req under fm matched none of the signatures data.
+func (v *Validator) diagnose(r *http.Request, req *CanonicalRequest, fm *format, hashes []namedHash, enc string, info *KeyInfo, hint string, data []string) string {
+	ts := req.Query.Get(v.params.Timestamp)
+	secrets := v.dateSecrets(v.secrets(info, hint), ts)
+	matches := func(s string, secrets []string) bool {
+		for _, alg := range hashes {
+			if v.anySignature(alg.fn, s, enc, secrets, data) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The query as sent, rather than sorted.
+	rest := fm.Canonical(&CanonicalRequest{Host: req.Host, Path: req.Path, IdempotencyKey: req.IdempotencyKey})
+	if matches(r.Method+":"+StripParams(r.URL.RawQuery, v.params.Signature)+rest[1:], secrets) {
+		return MismatchOrder
+	}
+	for _, version := range versions() {
+		f := formats[version]
+		if f == fm || f.path != fm.path {
+			continue
+		}
+		if matches(f.Canonical(req), secrets) {
+			if f.sortValues != fm.sortValues {
+				return MismatchOrder
+			}
+			return MismatchEncoding
+		}
+	}
+
+	// The path signed when it shouldn't be, or not when it should.
+	toggled := *fm
+	toggled.path = !fm.path
+	if matches(toggled.Canonical(req), secrets) {
+		return MismatchPath
+	}
+	alt := *req
+	for _, path := range []string{"/", r.URL.EscapedPath(), r.URL.Path} {
+		if fm.path && path != req.Path {
+			alt.Path = path
+			if matches(fm.Canonical(&alt), secrets) {
+				return MismatchPath
+			}
+		}
+	}
+
+	alt = *req
+	for _, host := range []string{"", requestHost(r)} {
+		if host == req.Host {
+			continue
+		}
+		alt.Host = host
+		if matches(fm.Canonical(&alt), secrets) {
+			return MismatchHost
+		}
+	}
+
+	if v.epoch > 0 {
+		now := v.clock.Now()
+		var skewed []string
+		for _, s := range info.secrets() {
+			skewed = append(skewed, EpochSecret(s, now.Add(v.epoch), v.epoch), EpochSecret(s, now.Add(-2*v.epoch), v.epoch))
+		}
+		if matches(fm.Canonical(req), v.dateSecrets(skewed, ts)) {
+			return MismatchSkew
+		}
+	}
+	if v.dates {
+		// Signed for the local rather than the UTC date.
+		if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+			for _, d := range []int64{-86400, 86400} {
+				if matches(fm.Canonical(req), v.dateSecrets(v.secrets(info, hint), strconv.FormatInt(sec+d, 10))) {
+					return MismatchSkew
+				}
+			}
+		}
+	}
+	return MismatchUnknown
+}
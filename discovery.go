@@ -0,0 +1,80 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// WellKnownPath is where the discovery document is conventionally served.
+const WellKnownPath = "/.well-known/hancock-configuration"
+
+// Discovery describes how a Validator expects requests to be signed, so
+// client SDKs can configure themselves.
+type Discovery struct {
+	Versions   []string `json:"versions"`
+	Algorithms []string `json:"algorithms"`
+	// Encoding is the signature encoding of requests without one, and
+	// Encodings those accepted (see SignEncoding).
+	Encoding  string   `json:"encoding"`
+	Encodings []string `json:"encodings"`
+	Params    Params   `json:"params"`
+	// Skew is the expiration duration, in seconds, of every key. It's left
+	// out when keys set their own, or timestamps aren't checked.
+	Skew         int    `json:"skew,omitempty"`
+	Idempotency  string `json:"idempotencyHeader,omitempty"`
+	HostBinding  bool   `json:"hostBinding,omitempty"`
+	TimeEndpoint string `json:"timeEndpoint,omitempty"`
+}
+
+// Discovery returns the Validator's discovery document.
+// timeEndpoint is the URL of a TimeHandler, if one is served.
+func (v *Validator) Discovery(timeEndpoint string) *Discovery {
+	d := &Discovery{
+		Versions:     v.acceptedVersions(),
+		Algorithms:   v.acceptedAlgorithms(),
+		Encoding:     Base64URL,
+		Encodings:    Encodings(),
+		Params:       v.params,
+		HostBinding:  v.bindHost,
+		TimeEndpoint: timeEndpoint,
+	}
+	if s := v.expireSeconds(); s > 0 {
+		d.Skew = s
+	}
+	if v.idem != nil {
+		d.Idempotency = IdempotencyKeyHeader
+	}
+	return d
+}
+
+// DiscoveryHandler returns a handler serving the Validator's discovery
+// document as JSON, to be mounted at WellKnownPath.
+func (v *Validator) DiscoveryHandler(timeEndpoint string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v.Discovery(timeEndpoint))
+	})
+}
+
+// TimeHandler returns a handler writing the server's time, as the Unix
+// timestamp used by the "ts" parameter, so clients can correct clock skew.
+func TimeHandler() http.Handler {
+	return timeHandler(SystemClock{})
+}
+
+// TimeHandler is TimeHandler writing the time of the Validator's clock.
+func (v *Validator) TimeHandler() http.Handler {
+	return timeHandler(v.clock)
+}
+
+func timeHandler(clock Clock) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(map[string]int64{"ts": clock.Now().UTC().Unix()})
+	})
+}
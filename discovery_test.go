@@ -0,0 +1,39 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiscovery(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		skew int
+	}{
+		{"key expiration", nil, 0},
+		{"expires", []Option{WithExpires(300)}, 300},
+		{"ignore expiration", []Option{WithExpires(-1)}, 0},
+		{"disabled", []Option{WithExpires(-2)}, 0},
+		{"policy", []Option{WithExpirePolicy(Expires(time.Minute))}, 60},
+	}
+	for _, tt := range tests {
+		d := NewValidator(nil, tt.opts...).Discovery("")
+		if d.Skew != tt.skew {
+			t.Errorf("%s: skew %d, want %d", tt.name, d.Skew, tt.skew)
+		}
+		if d.Encoding != Base64URL || !reflect.DeepEqual(d.Encodings, Encodings()) {
+			t.Errorf("%s: encodings %q %v", tt.name, d.Encoding, d.Encodings)
+		}
+	}
+	for _, enc := range []string{Base64URL, Base64URLNoPad, Hex, Base32} {
+		if !contains(Encodings(), enc) {
+			t.Errorf("%s not in Encodings", enc)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+)
+
+// WithDoubleHMAC compares signatures by comparing HMACs of them, keyed with
+// a random per-Validator key, in addition to comparing in constant time.
+// Any timing that does leak relates to values an attacker can't predict.
+func WithDoubleHMAC() Option {
+	return func(v *Validator) {
+		v.blind = make([]byte, 32)
+		if _, err := rand.Read(v.blind); err != nil {
+			panic("hancock: reading random key: " + err.Error())
+		}
+	}
+}
+
+// equal compares two MACs, or their encodings, in constant time.
+func (v *Validator) equal(a, b []byte) bool {
+	if v.blind == nil {
+		return hmac.Equal(a, b)
+	}
+	ha := hmac.New(sha256.New, v.blind)
+	ha.Write(a)
+	hb := hmac.New(sha256.New, v.blind)
+	hb.Write(b)
+	return hmac.Equal(ha.Sum(nil), hb.Sum(nil))
+}
@@ -0,0 +1,104 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// EmbedTokenHeader is the header embedded widgets send their token in.
+const EmbedTokenHeader = "X-Hancock-Embed-Token"
+
+// ErrOrigin is returned for tokens used from a different origin.
+var ErrOrigin = errors.New("hancock: token origin mismatch")
+
+// EmbedToken lets an embedded widget or iframe, hosted on Origin, call back
+// to the API on behalf of an API key without holding the key's secret.
+type EmbedToken struct {
+	Key     string   `json:"key"`
+	Origin  string   `json:"origin"`
+	Scopes  []string `json:"scopes,omitempty"`
+	Expires int64    `json:"exp"`
+}
+
+// MintEmbedToken returns an embed token for origin (e.g.
+// "https://partner.example"), valid for ttl, to hand to an embedded client,
+// typically through postMessage. opts may set the clock (see SignClock).
+func MintEmbedToken(key, pKey, origin string, scopes []string, ttl time.Duration, opts ...SignOption) string {
+	return signToken("embed", pKey, &EmbedToken{
+		Key:     key,
+		Origin:  origin,
+		Scopes:  scopes,
+		Expires: signNow(opts).Add(ttl).Unix(),
+	})
+}
+
+// ValidateEmbedToken validates an embed token used from origin, signed with
+// any of its key's secrets.
+func ValidateEmbedToken(token, origin string, keys KeyInfoFunc) (*EmbedToken, error) {
+	t, _, err := validateEmbedToken(token, origin, keys, SystemClock{})
+	return t, err
+}
+
+// ValidateEmbedToken is ValidateEmbedToken with v's keys and clock.
+func (v *Validator) ValidateEmbedToken(token, origin string) (*EmbedToken, error) {
+	t, _, err := validateEmbedToken(token, origin, v.keys, v.clock)
+	return t, err
+}
+
+func validateEmbedToken(token, origin string, keys KeyInfoFunc, clock Clock) (*EmbedToken, *KeyInfo, error) {
+	if keys == nil {
+		return nil, nil, ErrInvalidToken
+	}
+	t := new(EmbedToken)
+	if err := decodeToken(token, t); err != nil {
+		return nil, nil, err
+	}
+	info, err := keys(t.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info == nil || info.Secret == "" {
+		return nil, nil, ErrInvalidToken
+	}
+	info = keyed(info, t.Key)
+	if err := verifyKeyToken("embed", info, token); err != nil {
+		return nil, nil, err
+	}
+	if origin == "" || t.Origin != origin {
+		return nil, nil, ErrOrigin
+	}
+	if clock.Now().Unix() > t.Expires {
+		return nil, nil, ErrExpiredToken
+	}
+	return t, info, nil
+}
+
+// EmbedHandler returns a handler that validates the embed token sent in the
+// EmbedTokenHeader against the request's Origin header before invoking h.
+// The request context carries a KeyInfo limited to the scopes granted by
+// both the key and the token.
+func EmbedHandler(keys KeyInfoFunc, h http.Handler) http.Handler {
+	return embedHandler(keys, SystemClock{}, h)
+}
+
+// EmbedHandler is EmbedHandler with v's keys and clock.
+func (v *Validator) EmbedHandler(h http.Handler) http.Handler {
+	return embedHandler(v.keys, v.clock, h)
+}
+
+func embedHandler(keys KeyInfoFunc, clock Clock, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, key, err := validateEmbedToken(r.Header.Get(EmbedTokenHeader), r.Header.Get("Origin"), keys, clock)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		info := &KeyInfo{Key: t.Key, Scopes: narrowScopes(key.Scopes, t.Scopes)}
+		h.ServeHTTP(w, r.WithContext(NewContext(r.Context(), info)))
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEmbedHandler(t *testing.T) {
+	const origin = "https://partner.example"
+	keys := keyMap(map[string]*KeyInfo{
+		"key": {Key: "key", Secret: "secret", Previous: []string{"old"}, Scopes: []string{"read", "write"}},
+	})
+	var scopes []string
+	h := EmbedHandler(keys, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := FromContext(r.Context())
+		scopes = info.Scopes
+	}))
+	tests := []struct {
+		name   string
+		token  string
+		origin string
+		status int
+		scopes []string
+	}{
+		{"valid", MintEmbedToken("key", "secret", origin, []string{"read"}, time.Minute), origin, http.StatusOK, []string{"read"}},
+		{"previous secret", MintEmbedToken("key", "old", origin, []string{"read"}, time.Minute), origin, http.StatusOK, []string{"read"}},
+		// Tokens can't grant scopes their key doesn't have.
+		{"key scopes", MintEmbedToken("key", "secret", origin, []string{"read", "admin"}, time.Minute), origin, http.StatusOK, []string{"read"}},
+		{"wrong secret", MintEmbedToken("key", "other", origin, nil, time.Minute), origin, http.StatusUnauthorized, nil},
+		{"other origin", MintEmbedToken("key", "secret", origin, nil, time.Minute), "https://evil.example", http.StatusUnauthorized, nil},
+		{"expired", MintEmbedToken("key", "secret", origin, nil, -time.Minute), origin, http.StatusUnauthorized, nil},
+	}
+	for _, tt := range tests {
+		scopes = nil
+		r := httptest.NewRequest(http.MethodGet, "/widget", nil)
+		r.Header.Set(EmbedTokenHeader, tt.token)
+		r.Header.Set("Origin", tt.origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status || !reflect.DeepEqual(scopes, tt.scopes) {
+			t.Errorf("%s: %d %v, want %d %v", tt.name, w.Code, scopes, tt.status, tt.scopes)
+		}
+	}
+}
+
+func TestEmbedTokenClock(t *testing.T) {
+	const origin = "https://partner.example"
+	keys := keyMap(map[string]*KeyInfo{"key": {Key: "key", Secret: "secret"}})
+	clock := newTestClock()
+	v := NewValidator(nil, WithKeyInfo(keys), WithClock(clock))
+	// Minted on the test clock, years behind the system clock.
+	token := MintEmbedToken("key", "secret", origin, nil, time.Minute, SignClock(clock))
+	tests := []struct {
+		name  string
+		after time.Duration
+		valid bool
+	}{
+		{"valid", 0, true},
+		{"expired", 2 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		clock.now = time.Unix(1700000000, 0).Add(tt.after)
+		if _, err := v.ValidateEmbedToken(token, origin); (err == nil) != tt.valid {
+			t.Errorf("%s: %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+	if _, err := ValidateEmbedToken(token, origin, keys); err == nil {
+		t.Error("validated on the system clock")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// HMAC signature encodings, sent as the "enc" parameter. Requests without
+// it use Base64URL.
+const (
+	// Base64URL is padded base64url, and unpadded for truncated signatures
+	// (see SignTruncated).
+	Base64URL = "base64url"
+	// Base64URLNoPad is base64url without padding.
+	Base64URLNoPad = "base64url-nopad"
+	// Hex is hexadecimal, decoded whichever its case.
+	Hex = "hex"
+	// Base32 is unpadded base32, decoded whichever its case, for systems
+	// mangling both padding and case.
+	Base32 = "base32"
+)
+
+// Encoder serializes signatures, e.g. as base62 or Crockford's base32.
+type Encoder interface {
+	Encode(b []byte) string
+	Decode(s string) ([]byte, error)
+}
+
+type encoderFuncs struct {
+	encode func([]byte) string
+	decode func(string) ([]byte, error)
+}
+
+func (e encoderFuncs) Encode(b []byte) string          { return e.encode(b) }
+func (e encoderFuncs) Decode(s string) ([]byte, error) { return e.decode(s) }
+
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// rawBase64URL decodes base64url, padded or not.
+var rawBase64URL = encoderFuncs{
+	base64.RawURLEncoding.EncodeToString,
+	func(s string) ([]byte, error) {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	},
+}
+
+var encoders = struct {
+	sync.RWMutex
+	m map[string]Encoder
+}{m: map[string]Encoder{
+	Base64URL:      rawBase64URL,
+	Base64URLNoPad: rawBase64URL,
+	Hex:            encoderFuncs{hex.EncodeToString, hex.DecodeString},
+	Base32: encoderFuncs{base32NoPad.EncodeToString, func(s string) ([]byte, error) {
+		return base32NoPad.DecodeString(strings.ToUpper(strings.TrimRight(s, "=")))
+	}},
+}}
+
+// RegisterEncoding makes the signature encoding name available to signers
+// and validators.
+func RegisterEncoding(name string, e Encoder) {
+	encoders.Lock()
+	defer encoders.Unlock()
+	encoders.m[name] = e
+}
+
+// Encodings returns the registered signature encodings, sorted.
+func Encodings() []string {
+	encoders.RLock()
+	defer encoders.RUnlock()
+	names := make([]string, 0, len(encoders.m))
+	for name := range encoders.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encoder returns the Encoder of name, "" being Base64URL.
+func encoder(name string) (Encoder, bool) {
+	if name == "" {
+		name = Base64URL
+	}
+	encoders.RLock()
+	defer encoders.RUnlock()
+	e, ok := encoders.m[name]
+	return e, ok
+}
+
+// SignEncoding encodes HMAC signatures with the named encoding, e.g. Hex,
+// which must be registered.
+func SignEncoding(name string) SignOption {
+	return func(c *signConfig) {
+		c.encoding = name
+	}
+}
+
+// knownEncoding reports whether the encoding name is registered.
+func knownEncoding(name string) bool {
+	_, ok := encoder(name)
+	return ok
+}
+
+// encodeMAC returns mac encoded with the named encoding, truncated to n
+// bytes when n is set. Unknown encodings use Base64URL.
+func encodeMAC(mac []byte, n int, name string) string {
+	truncated := n > 0 && n < len(mac)
+	if truncated {
+		mac = mac[:n]
+	}
+	if name != "" && name != Base64URL {
+		if e, ok := encoder(name); ok {
+			return e.Encode(mac)
+		}
+	}
+	if truncated {
+		return base64.RawURLEncoding.EncodeToString(mac)
+	}
+	return base64.URLEncoding.EncodeToString(mac)
+}
+
+// decodeSignature decodes sig, encoded with the named encoding.
+func decodeSignature(sig, name string) ([]byte, error) {
+	e, ok := encoder(name)
+	if !ok {
+		return nil, fmt.Errorf("hancock: unknown encoding %q", name)
+	}
+	return e.Decode(sig)
+}
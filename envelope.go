@@ -0,0 +1,105 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrEnvelope is returned for envelopes not matching the envelope schema.
+	ErrEnvelope = errors.New("hancock: malformed envelope")
+	// ErrEnvelopeSignature is returned for envelopes whose signature doesn't match.
+	ErrEnvelopeSignature = errors.New("hancock: envelope signature mismatch")
+)
+
+// envelopeVersion is the version of the envelope schema.
+const envelopeVersion = 1
+
+// Envelope is a signed blob, e.g. an audit record or exported report, stored
+// to be verified later.
+type Envelope struct {
+	Version     int    `json:"v"`
+	Payload     []byte `json:"payload"`
+	ContentType string `json:"contentType"`
+	Timestamp   int64  `json:"ts"`
+	KeyID       string `json:"keyId"`
+	Signature   string `json:"sig"`
+}
+
+// SealEnvelope returns payload, of contentType (e.g. "application/json"),
+// signed by key. opts may set the clock (see SignClock).
+func SealEnvelope(key, pKey, contentType string, payload []byte, opts ...SignOption) *Envelope {
+	e := &Envelope{
+		Version:     envelopeVersion,
+		Payload:     payload,
+		ContentType: contentType,
+		Timestamp:   signNow(opts).Unix(),
+		KeyID:       key,
+	}
+	e.Signature = e.signature(pKey)
+	return e
+}
+
+// Marshal returns the JSON encoding of e.
+func (e *Envelope) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// UnmarshalEnvelope decodes an envelope, checking it against the envelope
+// schema: no unknown fields, a known version, and every field set.
+func UnmarshalEnvelope(b []byte) (*Envelope, error) {
+	e := new(Envelope)
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err := d.Decode(e); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrEnvelope, err)
+	}
+	switch {
+	case e.Version != envelopeVersion:
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrEnvelope, e.Version)
+	case e.ContentType == "", e.KeyID == "", e.Signature == "", e.Timestamp <= 0:
+		return nil, fmt.Errorf("%w: missing field", ErrEnvelope)
+	}
+	return e, nil
+}
+
+// Verify checks e's signature with the secrets of its key.
+func (e *Envelope) Verify(keys KeyInfoFunc) error {
+	info, err := keys(e.KeyID)
+	if err != nil {
+		return err
+	}
+	if info == nil || info.Secret == "" {
+		return fmt.Errorf("hancock: unknown envelope key `%s`", KeyAlias(e.KeyID))
+	}
+	for _, pKey := range info.secrets() {
+		if hmac.Equal([]byte(e.signature(pKey)), []byte(e.Signature)) {
+			return nil
+		}
+	}
+	return ErrEnvelopeSignature
+}
+
+// Time returns when e was sealed.
+func (e *Envelope) Time() time.Time {
+	return time.Unix(e.Timestamp, 0)
+}
+
+func (e *Envelope) signature(pKey string) string {
+	digest := sha256.Sum256(e.Payload)
+	hash := hmac.New(sha256.New, []byte(pKey))
+	fmt.Fprintf(hash, "envelope\n%d\n%s\n%d\n%s\n%s",
+		e.Version, e.ContentType, e.Timestamp, e.KeyID, hex.EncodeToString(digest[:]))
+	return base64.URLEncoding.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,46 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// WithEnvironment names the environment the Validator serves (e.g. "prod"
+// or "staging"). Keys declaring environments (KeyInfo.Environments) are
+// rejected in others, so a prod key pointed at staging fails clearly
+// rather than working by accident.
+func WithEnvironment(env string) Option {
+	return func(v *Validator) {
+		v.env = env
+	}
+}
+
+// checkEnvironment checks that info may be used in v's environment, and
+// against r's host.
+func (v *Validator) checkEnvironment(r *http.Request, info *KeyInfo) *Error {
+	if len(info.Environments) > 0 && !contains(info.Environments, v.env) {
+		env := v.env
+		if env == "" {
+			env = "unnamed"
+		}
+		return v.newError(http.StatusForbidden, r, "key `%s` is for %s, not the %s environment",
+			KeyAlias(info.Key), strings.Join(info.Environments, ", "), env)
+	}
+	if host := requestHost(r); len(info.Hosts) > 0 && !contains(info.Hosts, host) && !contains(info.Hosts, hostname(host)) {
+		return v.newError(http.StatusForbidden, r, "key `%s` not valid for host %s", KeyAlias(info.Key), host)
+	}
+	return nil
+}
+
+// hostname returns host without its port, if any.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
@@ -0,0 +1,39 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeyHosts(t *testing.T) {
+	v := NewValidator(nil, WithKeyInfo(keyMap(map[string]*KeyInfo{
+		"key":  {Key: "key", Secret: "secret", Expires: 300, Hosts: []string{"api.example.com"}},
+		"port": {Key: "port", Secret: "secret", Expires: 300, Hosts: []string{"api.example.com:8443"}},
+	})))
+	tests := []struct {
+		name   string
+		key    string
+		host   string
+		status int
+	}{
+		{"host", "key", "api.example.com", 0},
+		{"host case", "key", "API.example.com", 0},
+		{"host with port", "key", "api.example.com:8443", 0},
+		{"other host", "key", "www.example.com:8443", http.StatusForbidden},
+		{"port", "port", "api.example.com:8443", 0},
+		{"other port", "port", "api.example.com:9443", http.StatusForbidden},
+		{"no port", "port", "api.example.com", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, tt.key, "secret", "/", nil), nil)
+		r.Host = tt.host
+		if _, err := v.Validate(r); status(err) != tt.status {
+			t.Errorf("%s: status %d, want %d (%v)", tt.name, status(err), tt.status, err)
+		}
+	}
+}
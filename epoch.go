@@ -0,0 +1,72 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+// EpochSecret returns the secret derived from pKey for the epoch (a period
+// long time bucket) containing t. Signing with epoch secrets means a leaked
+// signing secret ages out on its own, without an explicit rotation.
+// Periods are whole seconds, those under a second being rounded up to one.
+func EpochSecret(pKey string, t time.Time, period time.Duration) string {
+	secs := int64(period / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	epoch := t.Unix() / secs
+	hash := hmac.New(sha256.New, []byte(pKey))
+	hash.Write([]byte("epoch:" + strconv.FormatInt(epoch, 10)))
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// SignEpoch signs with the current epoch's secret (see EpochSecret).
+// Periods under a second are rounded up to one.
+func SignEpoch(period time.Duration) SignOption {
+	return func(c *signConfig) {
+		c.epoch = epochPeriod(period)
+	}
+}
+
+// WithEpochs validates signatures made with the secrets of the current or
+// previous epoch (see SignEpoch), instead of the keys' secrets.
+// Periods under a second are rounded up to one.
+func WithEpochs(period time.Duration) Option {
+	return func(v *Validator) {
+		v.epoch = epochPeriod(period)
+	}
+}
+
+// epochPeriod returns period, or a second when it's positive but shorter.
+// Other periods disable epochs.
+func epochPeriod(period time.Duration) time.Duration {
+	if period > 0 && period < time.Second {
+		return time.Second
+	}
+	return period
+}
+
+// secrets returns the secrets a request signed by info may use. When the
+// request carries a key hint, only the matching secrets are returned.
+func (v *Validator) secrets(info *KeyInfo, hint string) []string {
+	secrets := info.secrets()
+	if hint != "" {
+		secrets = hintedSecrets(secrets, hint)
+	}
+	if v.epoch <= 0 {
+		return secrets
+	}
+	now := v.clock.Now()
+	derived := make([]string, 0, 2*len(secrets))
+	for _, s := range secrets {
+		derived = append(derived, EpochSecret(s, now, v.epoch), EpochSecret(s, now.Add(-v.epoch), v.epoch))
+	}
+	return derived
+}
@@ -0,0 +1,43 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEpochs(t *testing.T) {
+	clock := newTestClock()
+	keys := WithKeyInfo(keyMap(map[string]*KeyInfo{"key": {Key: "key", Secret: "secret", Expires: 300}}))
+	tests := []struct {
+		name   string
+		sign   time.Duration
+		valid  time.Duration
+		status int
+	}{
+		{"epochs", time.Hour, time.Hour, 0},
+		// Sub-second periods are a second, rather than dividing by zero.
+		{"sub-second", time.Millisecond, time.Millisecond, 0},
+		{"second", time.Millisecond, time.Second, 0},
+		{"unsigned epoch", 0, time.Hour, http.StatusUnauthorized},
+		{"disabled", time.Hour, 0, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		v := NewValidator(nil, keys, WithClock(clock), WithEpochs(tt.valid))
+		u := Sign(http.MethodGet, "key", "secret", "/", nil, SignClock(clock), SignEpoch(tt.sign))
+		_, err := v.Validate(httptest.NewRequest(http.MethodGet, u, nil))
+		if status(err) != tt.status {
+			t.Errorf("%s: status %d, want %d (%v)", tt.name, status(err), tt.status, err)
+		}
+	}
+	for _, period := range []time.Duration{0, -time.Second, time.Nanosecond} {
+		if EpochSecret("secret", clock.Now(), period) != EpochSecret("secret", clock.Now(), time.Second) {
+			t.Errorf("EpochSecret(%v) isn't a second's", period)
+		}
+	}
+}
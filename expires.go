@@ -0,0 +1,80 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SignExpires signs an absolute expiry, ttl from now, e.g. for links valid
+// for exactly 10 minutes. Validators then accept the request until it
+// expires, rather than for their expiry window past its timestamp.
+func SignExpires(ttl time.Duration) SignOption {
+	return func(c *signConfig) {
+		c.expires = ttl
+	}
+}
+
+// SignNotBefore signs a time the request isn't valid before, e.g. for
+// scheduled releases. Validators accept it for their expiry window (or
+// until it expires, see SignExpires) from then, rejecting earlier use with
+// a 425 status and CodeNotYetValid.
+func SignNotBefore(nbf time.Time) SignOption {
+	return func(c *signConfig) {
+		c.nbf = nbf
+	}
+}
+
+// WithMaxExpiry rejects requests expiring (see SignExpires) more than max
+// past their timestamp.
+func WithMaxExpiry(max time.Duration) Option {
+	return func(v *Validator) {
+		v.maxExpiry = max
+	}
+}
+
+// checkExpires verifies a request signed at ts hasn't passed its expiry
+// exp. Timestamps are allowed expireSeconds ahead, for clock skew, or any
+// time ahead when it's -1.
+func (v *Validator) checkExpires(r *http.Request, ts, exp string, expireSeconds int) *Error {
+	t, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return v.newError(http.StatusNotAcceptable, r, "invalid timestamp %s", ts)
+	}
+	e, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || e < t {
+		return v.newError(http.StatusBadRequest, r, "invalid expiry %s", exp)
+	}
+	now := v.clock.Now().Unix()
+	switch {
+	case expireSeconds >= 0 && t > now+int64(expireSeconds):
+		return v.newError(http.StatusNotAcceptable, r, "future timestamp %s", ts)
+	case now > e:
+		return v.newError(http.StatusNotAcceptable, r, "expired at %s", exp)
+	case v.maxExpiry > 0 && time.Duration(e-t)*time.Second > v.maxExpiry:
+		return v.newError(http.StatusNotAcceptable, r, "expiry %s exceeds maximum of %s", exp, v.maxExpiry)
+	}
+	return nil
+}
+
+// checkNotBefore verifies a request signed at ts is past nbf.
+func (v *Validator) checkNotBefore(r *http.Request, ts, nbf string) *Error {
+	t, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return v.newError(http.StatusNotAcceptable, r, "invalid timestamp %s", ts)
+	}
+	n, err := strconv.ParseInt(nbf, 10, 64)
+	if err != nil || n < t {
+		return v.newError(http.StatusBadRequest, r, "invalid not before %s", nbf)
+	}
+	if v.clock.Now().Unix() < n {
+		err := v.newError(http.StatusTooEarly, r, "not valid before %s", nbf)
+		err.Code = CodeNotYetValid
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExpirePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    *KeyInfo
+		opts   []Option
+		age    time.Duration // of the signature
+		signed string        // secret signed with
+		status int
+	}{
+		{"key expires", &KeyInfo{Expires: 300}, nil, 4 * time.Minute, "secret", 0},
+		{"key expired", &KeyInfo{Expires: 300}, nil, 6 * time.Minute, "secret", http.StatusNotAcceptable},
+		{"key policy", &KeyInfo{Expires: 300, Policy: Expires(time.Minute)}, nil, 2 * time.Minute, "secret", http.StatusNotAcceptable},
+		{"key grace", &KeyInfo{Policy: &ExpirePolicy{Window: time.Minute, Grace: time.Minute}}, nil, 90 * time.Second, "secret", 0},
+		{"key policy under a second", &KeyInfo{Expires: 300, Policy: Expires(time.Millisecond)}, nil, 0, "secret", http.StatusInternalServerError},
+		{"policy", &KeyInfo{Expires: -2}, []Option{WithExpirePolicy(Expires(5 * time.Minute))}, 4 * time.Minute, "secret", 0},
+		{"policy expired", &KeyInfo{Expires: -2}, []Option{WithExpirePolicy(Expires(5 * time.Minute))}, 6 * time.Minute, "secret", http.StatusNotAcceptable},
+		{"policy checks signatures", &KeyInfo{Expires: -2}, []Option{WithExpirePolicy(Expires(5 * time.Minute))}, 0, "other", http.StatusUnauthorized},
+		// Windows rounding to 0 don't fall back to the key's -2.
+		{"policy under a second", &KeyInfo{Expires: -2}, []Option{WithExpirePolicy(Expires(500 * time.Millisecond))}, 0, "other", http.StatusInternalServerError},
+		{"nil policy", &KeyInfo{Expires: -2}, []Option{WithExpirePolicy(nil)}, 0, "other", http.StatusInternalServerError},
+		{"no expiry", &KeyInfo{Expires: 300}, []Option{WithExpirePolicy(NoExpiry())}, 24 * time.Hour, "secret", 0},
+		{"disabled", &KeyInfo{Expires: 300}, []Option{WithExpirePolicy(Disabled())}, 0, "other", 0},
+		{"expires overrides key policy", &KeyInfo{Policy: Disabled()}, []Option{WithExpires(60)}, 0, "other", http.StatusUnauthorized},
+		{"expires overrides policy", &KeyInfo{}, []Option{WithExpirePolicy(Disabled()), WithExpires(60)}, 2 * time.Minute, "secret", http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		clock := newTestClock()
+		tt.key.Key, tt.key.Secret = "key", "secret"
+		v := NewValidator(nil, append([]Option{WithClock(clock), WithKeyInfo(keyMap(map[string]*KeyInfo{"key": tt.key}))}, tt.opts...)...)
+		signed := &testClock{now: clock.now.Add(-tt.age)}
+		r := httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, "key", tt.signed, "/", nil, SignClock(signed)), nil)
+		if _, err := v.Validate(r); status(err) != tt.status {
+			t.Errorf("%s: status %d, want %d (%v)", tt.name, status(err), tt.status, err)
+		}
+	}
+}
+
+func TestNoExpiryBounds(t *testing.T) {
+	clock := newTestClock()
+	tests := []struct {
+		name   string
+		opts   []Option
+		sign   []SignOption
+		status int
+	}{
+		{"no expiry", []Option{WithExpirePolicy(NoExpiry())}, nil, 0},
+		// Embargoed requests aren't valid early, whatever the key's expiry.
+		{"not before", []Option{WithExpirePolicy(NoExpiry())}, []SignOption{SignNotBefore(clock.now.Add(time.Hour))}, http.StatusTooEarly},
+		{"not before -1", []Option{WithExpires(-1)}, []SignOption{SignNotBefore(clock.now.Add(time.Hour))}, http.StatusTooEarly},
+		{"past not before", []Option{WithExpirePolicy(NoExpiry())}, []SignOption{SignNotBefore(clock.now.Add(-time.Hour))}, 0},
+		// Signed expiries are kept, whatever the key's expiry.
+		{"expires", []Option{WithExpirePolicy(NoExpiry())}, []SignOption{SignExpires(10 * time.Minute)}, http.StatusNotAcceptable},
+		{"expires -1", []Option{WithExpires(-1)}, []SignOption{SignExpires(10 * time.Minute)}, http.StatusNotAcceptable},
+		{"not expired", []Option{WithExpirePolicy(NoExpiry())}, []SignOption{SignExpires(48 * time.Hour)}, 0},
+		{"max expiry", []Option{WithExpirePolicy(NoExpiry()), WithMaxExpiry(time.Hour)}, []SignOption{SignExpires(48 * time.Hour)}, http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		v := NewValidator(nil, append([]Option{WithClock(clock), WithKeyInfo(keyMap(map[string]*KeyInfo{
+			"key": {Key: "key", Secret: "secret", Expires: 300},
+		}))}, tt.opts...)...)
+		signed := &testClock{now: clock.now.Add(-24 * time.Hour)}
+		r := httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, "key", "secret", "/", nil, append([]SignOption{SignClock(signed)}, tt.sign...)...), nil)
+		if _, err := v.Validate(r); status(err) != tt.status {
+			t.Errorf("%s: status %d, want %d (%v)", tt.name, status(err), tt.status, err)
+		}
+	}
+}
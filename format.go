@@ -0,0 +1,204 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Canonical string versions, sent as the "v" parameter. Requests without
+// it use Version1.
+const (
+	// Version1 signs `METHOD:QUERY_STRING`, the query encoded as by
+	// url.Values.Encode. Repeated parameters keep the order they're sent
+	// in, `id=1&id=2` and `id=2&id=1` sign differently.
+	Version1 = "1"
+	// Version2 is Version1 with empty values normalized: `?flag` and
+	// `?flag=` both canonicalize as `flag`, whichever form a client sends.
+	Version2 = "2"
+	// Version3 is Version2 with repeated parameters sorted by value, for
+	// clients (and proxies) that don't preserve the order of arrays.
+	Version3 = "3"
+	// Version4 is Version3 with the URL path signed, so a signature for
+	// `/admin/delete` isn't also valid for `/public/read`.
+	Version4 = "4"
+	// Version5 is Version4 with the path's percent-encoding normalized,
+	// for proxies re-encoding paths: escapes are uppercased, `%2f` is
+	// `%2F`, and unreserved characters unescaped, `%7E` is `~`. Queries
+	// are decoded before signing by every version, `+` and `%20` sign the
+	// same.
+	Version5 = "5"
+)
+
+// format is a version of the canonical string.
+type format struct {
+	version    string
+	bareEmpty  bool // empty values encode as `key`, not `key=`
+	sortValues bool // repeated values are sorted, not kept in order
+	path       bool // the escaped URL path is signed
+	escapes    bool // the path's percent-encoding is normalized
+}
+
+var formats = map[string]*format{
+	Version1: {version: Version1},
+	Version2: {version: Version2, bareEmpty: true},
+	Version3: {version: Version3, bareEmpty: true, sortValues: true},
+	Version4: {version: Version4, bareEmpty: true, sortValues: true, path: true},
+	Version5: {version: Version5, bareEmpty: true, sortValues: true, path: true, escapes: true},
+}
+
+// formatFor returns the format of version, "" being Version1.
+func formatFor(version string) (*format, bool) {
+	if version == "" {
+		version = Version1
+	}
+	f, ok := formats[version]
+	return f, ok
+}
+
+// WithVersions only accepts requests signed with the given canonical string
+// versions, e.g. Version4 once every client signs paths. Every known version
+// is accepted by default, requests without a version being Version1.
+func WithVersions(versions ...string) Option {
+	return func(v *Validator) {
+		v.versions = append([]string(nil), versions...)
+	}
+}
+
+// formatFor returns the format of version, when v accepts it.
+func (v *Validator) formatFor(version string) (*format, bool) {
+	if version == "" {
+		version = Version1
+	}
+	if v.versions != nil && !contains(v.versions, version) {
+		return nil, false
+	}
+	return formatFor(version)
+}
+
+// acceptedVersions returns the versions v accepts, oldest first.
+func (v *Validator) acceptedVersions() []string {
+	if v.versions == nil {
+		return versions()
+	}
+	var vs []string
+	for _, version := range versions() {
+		if contains(v.versions, version) {
+			vs = append(vs, version)
+		}
+	}
+	return vs
+}
+
+// versions returns the known versions, oldest first.
+func versions() []string {
+	vs := make([]string, 0, len(formats))
+	for v := range formats {
+		vs = append(vs, v)
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+// Canonical returns the string signed for req, `METHOD:QUERY_STRING`
+// followed by the host, when bound, the path, when f signs it, and the
+// idempotency key, if any.
+func (f *format) Canonical(req *CanonicalRequest) string {
+	s := req.Method + ":" + f.encode(req.Query)
+	if req.Host != "" {
+		s += "\nhost:" + req.Host
+	}
+	if f.path {
+		path := req.Path
+		if path == "" {
+			path = "/"
+		}
+		if f.escapes {
+			path = normalizeEscapes(path)
+		}
+		s += "\npath:" + path
+	}
+	if req.IdempotencyKey != "" {
+		s += "\nidempotency-key:" + req.IdempotencyKey
+	}
+	return s
+}
+
+// encode encodes q sorted by key, and by value when f.sortValues.
+func (f *format) encode(q url.Values) string {
+	if !f.bareEmpty && !f.sortValues {
+		return q.Encode()
+	}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		ek := url.QueryEscape(k)
+		vals := q[k]
+		if f.sortValues && len(vals) > 1 {
+			vals = append([]string(nil), vals...)
+			sort.Strings(vals)
+		}
+		for _, val := range vals {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(ek)
+			if val != "" || !f.bareEmpty {
+				b.WriteByte('=')
+				b.WriteString(url.QueryEscape(val))
+			}
+		}
+	}
+	return b.String()
+}
+
+// normalizeEscapes returns p with its escapes uppercased, and those of
+// unreserved characters decoded.
+func normalizeEscapes(p string) string {
+	if !strings.Contains(p, "%") {
+		return p
+	}
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] != '%' || i+2 >= len(p) || !isHex(p[i+1]) || !isHex(p[i+2]) {
+			b.WriteByte(p[i])
+			continue
+		}
+		c := unhex(p[i+1])<<4 | unhex(p[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(p[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+// isUnreserved reports whether c is an RFC 3986 unreserved character.
+func isUnreserved(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return c == '-' || c == '.' || c == '_' || c == '~'
+}
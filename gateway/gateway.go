@@ -0,0 +1,148 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gateway carries hancock identities across a grpc-gateway, which
+// transcodes REST calls to gRPC and loses their signed URLs on the way.
+//
+// The gateway validates requests with hancock, then forwards the identity
+// as metadata, re-signed with a secret shared with the gRPC servers:
+//
+//	mux := runtime.NewServeMux(runtime.WithMetadata(gateway.Metadata(secret)))
+//	http.ListenAndServe(addr, validator.Handler(mux))
+//
+// Servers trust only identities re-signed by the gateway:
+//
+//	grpc.NewServer(
+//		grpc.UnaryInterceptor(gateway.UnaryServerInterceptor(secret, time.Minute)),
+//		grpc.StreamInterceptor(gateway.StreamServerInterceptor(secret, time.Minute)),
+//	)
+//
+// It's kept separate from hancock so the core package stays free of the
+// gRPC dependency.
+package gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.minty.io/hancock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys of the forwarded identity.
+const (
+	KeyMetadata       = "hancock-key"
+	ScopesMetadata    = "hancock-scopes"
+	TierMetadata      = "hancock-tier"
+	TimestampMetadata = "hancock-ts"
+	SignatureMetadata = "hancock-signature"
+)
+
+var (
+	errMissing   = errors.New("gateway: missing identity")
+	errExpired   = errors.New("gateway: expired identity")
+	errSignature = errors.New("gateway: identity signature mismatch")
+)
+
+// Metadata returns a grpc-gateway metadata annotator (see
+// runtime.WithMetadata) forwarding the identity of requests validated by
+// hancock (see hancock.FromContext), signed with secret. Requests without
+// one forward nothing.
+func Metadata(secret []byte) func(context.Context, *http.Request) metadata.MD {
+	return func(ctx context.Context, r *http.Request) metadata.MD {
+		info, ok := hancock.FromContext(r.Context())
+		if !ok {
+			return nil
+		}
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		scopes := strings.Join(info.Scopes, ",")
+		return metadata.Pairs(
+			KeyMetadata, info.Key,
+			ScopesMetadata, scopes,
+			TierMetadata, info.Tier,
+			TimestampMetadata, ts,
+			SignatureMetadata, signature(secret, info.Key, scopes, info.Tier, ts),
+		)
+	}
+}
+
+// UnaryServerInterceptor rejects calls without an identity signed by the
+// gateway with secret within maxAge, adding the identity to the context of
+// those it passes (see hancock.FromContext).
+func UnaryServerInterceptor(secret []byte, maxAge time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		info, err := verify(ctx, secret, maxAge)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(hancock.NewContext(ctx, info), req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams.
+func StreamServerInterceptor(secret []byte, maxAge time.Duration) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		info, err := verify(ss.Context(), secret, maxAge)
+		if err != nil {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(srv, &stream{ss, hancock.NewContext(ss.Context(), info)})
+	}
+}
+
+type stream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *stream) Context() context.Context {
+	return s.ctx
+}
+
+// verify returns the identity forwarded in ctx's metadata.
+func verify(ctx context.Context, secret []byte, maxAge time.Duration) (*hancock.KeyInfo, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(k string) string {
+		if v := md.Get(k); len(v) == 1 {
+			return v[0]
+		}
+		return ""
+	}
+	key, scopes, tier, ts, sig := get(KeyMetadata), get(ScopesMetadata), get(TierMetadata), get(TimestampMetadata), get(SignatureMetadata)
+	if key == "" || sig == "" {
+		return nil, errMissing
+	}
+	t, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, errMissing
+	}
+	if age := time.Since(time.Unix(t, 0)); age > maxAge || age < -maxAge {
+		return nil, errExpired
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, key, scopes, tier, ts))) {
+		return nil, fmt.Errorf("%w for key `%s`", errSignature, hancock.KeyAlias(key))
+	}
+	info := &hancock.KeyInfo{Key: key, Tier: tier}
+	if scopes != "" {
+		info.Scopes = strings.Split(scopes, ",")
+	}
+	return info, nil
+}
+
+func signature(secret []byte, key, scopes, tier, ts string) string {
+	hash := hmac.New(sha256.New, secret)
+	fmt.Fprintf(hash, "gateway\n%s\n%s\n%s\n%s", key, scopes, tier, ts)
+	return base64.URLEncoding.EncodeToString(hash.Sum(nil))
+}
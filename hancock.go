@@ -3,20 +3,42 @@
 // license that can be found in the LICENSE file.
 
 // Hancock signs and validates URL/Requests.
+//
+// The hancock package only depends on the standard library. Integrations
+// with third party frameworks and stores live in their own subpackages
+// (e.g. wrappers for dingo), so they're only pulled in when imported.
+//
+// Requests are validated by a Validator, configured with options, e.g.
+// the accepted algorithms, versions and canonicalization, the carrier of the
+// signing parameters, the clock or the replay store:
+//
+//	v := hancock.NewValidator(keyFn,
+//		hancock.WithAlgorithms(hancock.SHA256),
+//		hancock.WithVersions(hancock.Version4),
+//		hancock.WithCarrier(hancock.AuthorizationCarrier{}),
+//		hancock.WithReplayStore(hancock.NewMemoryReplayStore(100000)),
+//	)
+//	q, err := v.Validate(r)
+//
+// The package level Validate is a shorthand for a single secret, using the
+// defaults.
 package hancock
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// RequestInfo describes the request that failed validation.
+// APIKey is the key's alias (see KeyAlias), never the raw key.
 type RequestInfo struct {
 	APIKey     string      `json:"apiKey"`
 	Host       string      `json:"host"`
@@ -33,31 +55,37 @@ type Error struct {
 	Status  int         `json:"status"`
 	Message string      `json:"message"`
 	Request RequestInfo `json:"request"`
+	// Reasons lists every failed check when validating with WithAllErrors.
+	Reasons []string `json:"reasons,omitempty"`
+	// Code identifies errors clients may act on, e.g. CodeNotYetValid.
+	Code string `json:"code,omitempty"`
 }
 
+// CodeNotYetValid is the Code of requests used before they're valid (see
+// SignNotBefore).
+const CodeNotYetValid = "not_yet_valid"
+
+// StatusClientClosedRequest is the status used when the client goes away
+// before validation completes.
+const StatusClientClosedRequest = 499
+
 type LogFunc func(...interface{})
 
 // KeyFunc returns the matching private key, and expiration duration,
 // for the given public key
 type KeyFunc func(key string) (pKey string, expires int)
 
-type signedHandler struct {
-	handler http.Handler
-	key     KeyFunc
-	Log     LogFunc
-}
-
 // Error returns the error message.
 func (e Error) Error() string {
 	return e.Message
 }
 
-func isValidTS(ts string, expireSeconds int) (string, bool) {
+func isValidTS(ts string, expireSeconds int, now time.Time) (string, bool) {
 	if ts == "" {
 		return "missing", false
 	}
 	if t, err := strconv.ParseInt(ts, 10, 64); err == nil {
-		now := time.Now().UTC().Unix()
+		now := now.UTC().Unix()
 		dur := now - t
 		if dur < 0 {
 			dur = dur * -1
@@ -78,44 +106,159 @@ func isValidTS(ts string, expireSeconds int) (string, bool) {
 // When `expireSeconds` is -2 the security check is skipped altogether (everything is valid)
 // ** 0 was not used as it's the default value for ints, and could allow attacks
 //    when `expireSeconds` is not set properly
+//
+// Validation stops early, with a 499 (client gone) or 504 (deadline) status,
+// once r's context is canceled or past its deadline.
+//
+// Validate uses a Validator's defaults, other configurations need one (see
+// NewValidator), which also takes an ExpirePolicy rather than the magic
+// numbers (see WithExpirePolicy).
 func Validate(r *http.Request, pKey string, expireSeconds int) (url.Values, *Error) {
-	v := r.URL.Query()
-	switch expireSeconds {
-	default: // Validate expire seconds is in range
-		ts := v.Get("ts")
-		if s, ok := isValidTS(ts, expireSeconds); !ok {
-			return nil, newError(http.StatusNotAcceptable, r, "%s timestamp %s", s, ts)
-		}
-	case -1: // Ignore expire time
-		// pass
-	case -2: // Disable security altogether
-		v.Del("data")
-		v.Del("apikey")
-		v.Del("ts")
-		return v, nil
+	er, err := defaultValidator.extract(r)
+	if err != nil {
+		return nil, err
 	}
+	q, err := defaultValidator.validate(er, &KeyInfo{Secret: pKey}, expireSeconds)
+	keepBody(r, er)
+	return q, err
+}
 
-	// Generate `METHOD:QUERY_STRING` string for hashing (removing `data` param)
-	data := v.Get("data")
-	v.Del("data")
-	sig := fmt.Sprintf("%s:%s", r.Method, v.Encode())
+// SignOption configures SignQS and Sign.
+type SignOption func(*signConfig)
 
-	// Validate hash
-	hash := hmac.New(sha256.New, []byte(pKey))
-	hash.Write([]byte(sig))
-	encHash := base64.URLEncoding.EncodeToString(hash.Sum(nil))
-	if encHash != data {
-		return nil, newError(http.StatusUnauthorized, r, "signature mismatch `%s` != `%s`", encHash, data)
+type signConfig struct {
+	params  Params
+	also    []string
+	idemKey string
+	path    string
+	host    string
+	paths   PathNormalization
+	body    string
+	alg     string
+	epoch   time.Duration
+	hint    bool
+	version string
+	nonce   string
+	expires time.Duration
+	nbf     time.Time
+	claims  Claims
+	maxSize string
+
+	rawECDSA      bool
+	truncate      int
+	encoding      string
+	canonicalizer Canonicalizer
+	header        http.Header
+	dates         bool
+	service       string
+	clock         Clock
+}
+
+// SignParams sets the names of the signing parameters, which must match
+// those the validator expects. Unset names keep their DefaultParams name.
+func SignParams(p Params) SignOption {
+	return func(c *signConfig) {
+		c.params = p.withDefaults()
 	}
+}
 
-	// Remove remaining signature params
-	v.Del("apikey")
-	v.Del("ts")
-	return v, nil
+// SignKeyHint adds a short hint identifying pKey, so validators holding
+// several secrets for the key (rotation, epochs) check the right one first.
+func SignKeyHint() SignOption {
+	return func(c *signConfig) {
+		c.hint = true
+	}
+}
+
+// SignVersion signs with the canonical string version, sent as the "v"
+// parameter (see Version1). Validators reject versions they don't know or
+// accept (see WithVersions).
+func SignVersion(version string) SignOption {
+	return func(c *signConfig) {
+		c.version = version
+	}
+}
+
+// SignPath sets the escaped URL path signed by versions that sign it (see
+// Version4). Sign takes it from its URL.
+func SignPath(path string) SignOption {
+	return func(c *signConfig) {
+		c.path = path
+	}
+}
+
+// SignHost binds the signature to host (e.g. "api.example.com"), for
+// validators using WithHostBinding.
+func SignHost(host string) SignOption {
+	return func(c *signConfig) {
+		c.host = strings.ToLower(host)
+	}
+}
+
+// SignAlsoWith adds a signature made with each of pKeys. During a secret
+// rotation clients sign with both the old and new secrets, so requests are
+// accepted whichever one the validator has.
+func SignAlsoWith(pKeys ...string) SignOption {
+	return func(c *signConfig) {
+		c.also = append(c.also, pKeys...)
+	}
+}
+
+// SignIdempotencyKey binds k, which must be sent as the request's
+// Idempotency-Key header, into the signature.
+func SignIdempotencyKey(k string) SignOption {
+	return func(c *signConfig) {
+		c.idemKey = k
+	}
 }
 
 // SignQS returns a signed query-string from the given "qs".
-func SignQS(method, key, pKey string, values url.Values) string {
+func SignQS(method, key, pKey string, values url.Values, opts ...SignOption) string {
+	return signValues(method, key, pKey, values, opts).Encode()
+}
+
+// signValues returns a copy of values with the signing parameters added.
+func signValues(method, key, pKey string, values url.Values, opts []SignOption) url.Values {
+	c := newSignConfig(opts)
+	now := c.clock.Now()
+	var hint string
+	if c.hint {
+		hint = keyHint(pKey)
+	}
+	v, sig := c.prepare(method, key, hint, values, now)
+
+	for _, name := range strings.Split(c.alg, ",") {
+		// Unknown algorithms are sent as is, for the validator to reject.
+		alg, ok := algorithm(name)
+		if !ok {
+			alg = sha256.New
+		}
+		for _, k := range append([]string{pKey}, c.also...) {
+			if c.epoch > 0 {
+				k = EpochSecret(k, now, c.epoch)
+			}
+			if c.dates {
+				k = DateSecret(k, now, c.service)
+			}
+			hash := hmac.New(alg, []byte(k))
+			hash.Write([]byte(sig))
+			v.Add(c.params.Signature, encodeMAC(hash.Sum(nil), c.truncate, c.encoding))
+		}
+	}
+	return v
+}
+
+func newSignConfig(opts []SignOption) *signConfig {
+	c := &signConfig{params: DefaultParams, clock: SystemClock{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// prepare returns a copy of values with the signing parameters, but the
+// signature, added, and the canonical string to sign.
+func (c *signConfig) prepare(method, key, hint string, values url.Values, now time.Time) (url.Values, string) {
 	v := make(url.Values)
 	if values != nil {
 		for k, o := range values {
@@ -123,54 +266,95 @@ func SignQS(method, key, pKey string, values url.Values) string {
 		}
 	}
 
-	v.Add("apikey", key)
-	v.Add("ts", fmt.Sprintf("%d", time.Now().UTC().Unix()))
-
-	// Generate signature
-	enc := v.Encode() // Encode sorts by keys (I think this was added with 1.2'ish?)
-	sig := fmt.Sprintf("%s:%s", method, enc)
-	hash := hmac.New(sha256.New, []byte(pKey))
-	hash.Write([]byte(sig))
-	encHash := base64.URLEncoding.EncodeToString(hash.Sum(nil))
+	v.Add(c.params.APIKey, key)
+	v.Add(c.params.Timestamp, fmt.Sprintf("%d", now.UTC().Unix()))
+	if c.expires > 0 {
+		v.Add(c.params.Expires, fmt.Sprintf("%d", now.Add(c.expires).UTC().Unix()))
+	}
+	if !c.nbf.IsZero() {
+		v.Add(c.params.NotBefore, fmt.Sprintf("%d", c.nbf.UTC().Unix()))
+	}
+	if len(c.claims) > 0 {
+		v.Add(c.params.Claims, c.claims.encode())
+	}
+	if c.maxSize != "" {
+		v.Add(c.params.MaxSize, c.maxSize)
+	}
+	if hint != "" {
+		v.Add(c.params.KeyHint, hint)
+	}
+	if c.body != "" {
+		v.Add(c.params.BodyHash, c.body)
+	}
+	if c.alg != "" && c.alg != SHA256 {
+		v.Add(c.params.Algorithm, c.alg)
+	}
+	if c.encoding != "" && c.encoding != Base64URL {
+		v.Add(c.params.Encoding, c.encoding)
+	}
+	if c.nonce != "" {
+		v.Add(c.params.Nonce, c.nonce)
+	}
+	// Unknown versions are sent as is, for the validator to reject.
+	f, ok := formatFor(c.version)
+	if !ok {
+		f = formats[Version1]
+	}
+	if c.version != "" {
+		v.Add(c.params.Version, c.version)
+	}
 
-	v.Add("data", encHash)
-	return v.Encode()
+	return v, canonicalizer(c.canonicalizer, f).Canonical(&CanonicalRequest{
+		Method:         method,
+		Host:           c.host,
+		Path:           c.paths.apply(c.path),
+		Query:          v,
+		IdempotencyKey: c.idemKey,
+		Header:         c.header,
+	})
 }
 
 // Sign returns a signed URL.
-func Sign(method, key, pKey, urlStr string, qs url.Values) string {
-	return fmt.Sprintf("%s?%s", urlStr, SignQS(method, key, pKey, qs))
+func Sign(method, key, pKey, urlStr string, qs url.Values, opts ...SignOption) string {
+	if u, err := url.Parse(urlStr); err == nil {
+		opts = append([]SignOption{SignPath(u.EscapedPath())}, opts...)
+	}
+	return fmt.Sprintf("%s?%s", urlStr, SignQS(method, key, pKey, qs, opts...))
 }
 
-func (h *signedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	key := r.URL.Query().Get("apikey")
-	pKey, expires := h.key(key)
-	if pKey == "" {
-		w.WriteHeader(http.StatusUnauthorized)
-		return
-	} else if _, err := Validate(r, pKey, expires); err != nil {
-		w.WriteHeader(err.Status)
-		h.Log(err)
-		return
-	}
-	h.handler.ServeHTTP(w, r)
+// SignedHandler returns a handler that validates requests, with the keys
+// returned by keyFn, before invoking h, e.g. with WithExpirePolicy.
+func SignedHandler(h http.Handler, keyFn KeyFunc, logFn LogFunc, opts ...Option) http.Handler {
+	return NewValidator(keyFn, append([]Option{WithLog(logFn)}, opts...)...).Handler(h)
 }
 
-func SignedHandler(h http.Handler, keyFn KeyFunc, logFn LogFunc) http.Handler {
-	return &signedHandler{h, keyFn, logFn}
+// ctxError returns an error when r's context is canceled or past its deadline.
+func ctxError(r *http.Request) *Error {
+	switch r.Context().Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return newError(http.StatusGatewayTimeout, r, "validation deadline exceeded")
+	default:
+		return newError(StatusClientClosedRequest, r, "client closed request")
+	}
 }
 
 func newError(status int, r *http.Request, fmtStr string, params ...interface{}) *Error {
-	header, _ := json.Marshal(r.Header)
+	return defaultValidator.newError(status, r, fmtStr, params...)
+}
+
+func (v *Validator) newError(status int, r *http.Request, fmtStr string, params ...interface{}) *Error {
+	header, _ := json.Marshal(v.redactedHeader(r))
 	return &Error{
 		Status:  status,
 		Message: fmt.Sprintf(fmtStr, params...),
 		Request: RequestInfo{
-			r.URL.Query().Get("apikey"),
-			r.Host,
+			KeyAlias(r.URL.Query().Get(v.params.APIKey)),
+			requestHost(r),
 			r.Proto,
-			r.RemoteAddr,
-			r.RequestURI,
+			remoteAddrString(r),
+			aliasURI(requestURI(r), v.params),
 			string(header),
 		},
 	}
@@ -0,0 +1,71 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// testClock is a Clock tests set.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// newTestClock returns a clock at a fixed time.
+func newTestClock() *testClock {
+	return &testClock{now: time.Unix(1700000000, 0)}
+}
+
+// keyMap looks up keys in m.
+func keyMap(m map[string]*KeyInfo) KeyInfoFunc {
+	return func(key string) (*KeyInfo, error) {
+		return m[key], nil
+	}
+}
+
+// status returns the status of err, 0 when nil.
+func status(err *Error) int {
+	if err == nil {
+		return 0
+	}
+	return err.Status
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		signed  string
+		pKey    string
+		expires int
+		status  int
+	}{
+		{"valid", http.MethodGet, "secret", "secret", 300, 0},
+		{"wrong secret", http.MethodGet, "other", "secret", 300, http.StatusUnauthorized},
+		{"wrong method", http.MethodPost, "secret", "secret", 300, http.StatusUnauthorized},
+		{"empty secret", http.MethodGet, "", "", 300, http.StatusUnauthorized},
+		{"skip time", http.MethodGet, "secret", "secret", -1, 0},
+		{"disabled", http.MethodGet, "other", "secret", -2, 0},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, Sign(http.MethodGet, "key", tt.signed, "/a", url.Values{"b": {"c"}}), nil)
+		q, err := Validate(r, tt.pKey, tt.expires)
+		if got := status(err); got != tt.status {
+			t.Errorf("%s: status %d, want %d (%v)", tt.name, got, tt.status, err)
+			continue
+		}
+		if err == nil && tt.expires != -2 && (q.Get("b") != "c" || q.Get("apikey") != "") {
+			t.Errorf("%s: query %v", tt.name, q)
+		}
+	}
+}
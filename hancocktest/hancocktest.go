@@ -0,0 +1,49 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hancocktest provides utilities for testing code signing and
+// validating with hancock.
+//
+//	clock := hancocktest.NewClock(time.Unix(1400000000, 0))
+//	u := hancock.Sign("GET", key, pKey, urlStr, nil, hancock.SignClock(clock))
+//	v := hancock.NewValidator(keyFn, hancock.WithClock(clock))
+//	clock.Advance(10 * time.Minute) // u is now expired
+package hancocktest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a hancock.Clock whose time only changes when set.
+type Clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// NewClock returns a Clock set to t.
+func NewClock(t time.Time) *Clock {
+	return &Clock{t: t}
+}
+
+// Now returns the clock's time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// Set sets the clock's time to t.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	c.t = t
+	c.mu.Unlock()
+}
+
+// Advance moves the clock's time by d, backwards when negative.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
@@ -0,0 +1,62 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NewHashedSecret returns a new secret for key, and the value to store in
+// place of it. The secret is derived from the stored value with HKDF, salted
+// by pepper, which is kept out of the key database (e.g. in the service's
+// configuration) so a leaked database alone doesn't expose secrets.
+func NewHashedSecret(key string, pepper []byte) (stored, secret string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	stored = base64.RawURLEncoding.EncodeToString(b)
+	secret, err = hashedSecret(key, stored, pepper)
+	return stored, secret, err
+}
+
+// HashedKeys wraps a KeyInfoFunc whose KeyInfo.Secret (and Previous) hold
+// values stored by NewHashedSecret, deriving the actual secrets from them.
+func HashedKeys(fn KeyInfoFunc, pepper []byte) KeyInfoFunc {
+	return func(key string) (*KeyInfo, error) {
+		info, err := fn(key)
+		if err != nil || info == nil || info.Secret == "" {
+			return info, err
+		}
+		derived := *info
+		if derived.Secret, err = hashedSecret(key, info.Secret, pepper); err != nil {
+			return nil, err
+		}
+		derived.Previous = make([]string, 0, len(info.Previous))
+		for _, p := range info.Previous {
+			// An empty stored value would derive a secret from the pepper alone.
+			if p == "" {
+				continue
+			}
+			s, err := hashedSecret(key, p, pepper)
+			if err != nil {
+				return nil, err
+			}
+			derived.Previous = append(derived.Previous, s)
+		}
+		return &derived, nil
+	}
+}
+
+func hashedSecret(key, stored string, pepper []byte) (string, error) {
+	b, err := hkdf.Key(sha256.New, []byte(stored), pepper, "hancock:"+key, 32)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
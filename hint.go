@@ -0,0 +1,32 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// keyHint returns a 4 byte, hex encoded, hint identifying pKey.
+func keyHint(pKey string) string {
+	sum := sha256.Sum256([]byte(pKey))
+	return hex.EncodeToString(sum[:4])
+}
+
+// hintedSecrets returns the secrets matching hint, or all of them when none
+// match (e.g. the hint names a secret the validator no longer holds, while
+// another signature of the request uses one it does).
+func hintedSecrets(secrets []string, hint string) []string {
+	var hinted []string
+	for _, s := range secrets {
+		if keyHint(s) == hint {
+			hinted = append(hinted, s)
+		}
+	}
+	if len(hinted) == 0 {
+		return secrets
+	}
+	return hinted
+}
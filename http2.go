@@ -0,0 +1,42 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HTTP/2 (and h2c) requests carry their host in the :authority
+// pseudo-header, and their target in :path. net/http maps them onto
+// r.Host and r.RequestURI, but requests built in process (e.g. handlers
+// invoked directly, or behind some h2c bridges) may only set r.URL.
+
+// requestHost returns the host r was sent to, lowercased.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if host == "" && r.URL != nil {
+		host = r.URL.Host
+	}
+	return strings.ToLower(host)
+}
+
+// requestURI returns r's request target.
+func requestURI(r *http.Request) string {
+	if r.RequestURI != "" || r.URL == nil {
+		return r.RequestURI
+	}
+	return r.URL.RequestURI()
+}
+
+// headerOrTrailer returns the named header, or else trailer, which is only
+// set once r.Body has been read to EOF. Streaming senders send values
+// computed over the body (signatures, digests) as trailers.
+func headerOrTrailer(r *http.Request, name string) string {
+	if v := r.Header.Get(name); v != "" {
+		return v
+	}
+	return r.Trailer.Get(name)
+}
@@ -0,0 +1,119 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newHTTP2Server returns a TLS server speaking HTTP/2 to its client.
+func newHTTP2Server(t *testing.T, h http.Handler) *httptest.Server {
+	srv := httptest.NewUnstartedServer(h)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTP2HostBinding(t *testing.T) {
+	v := NewValidator(nil, WithHostBinding(""), WithKeyInfo(keyMap(map[string]*KeyInfo{
+		"key": {Key: "key", Secret: "secret", Expires: 300},
+	})))
+	var proto string
+	srv := newHTTP2Server(t, v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.Proto
+	})))
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	tests := []struct {
+		name   string
+		host   string
+		status int
+	}{
+		// The host is checked against :authority.
+		{"authority", host, http.StatusOK},
+		{"host case", strings.ToUpper(host), http.StatusOK},
+		{"other host", "api.example.com", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		proto = ""
+		res, err := srv.Client().Get(Sign(http.MethodGet, "key", "secret", srv.URL+"/", nil, SignHost(tt.host)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, res.StatusCode, tt.status)
+		}
+		if tt.status == http.StatusOK && proto != "HTTP/2.0" {
+			t.Errorf("%s: served over %q", tt.name, proto)
+		}
+	}
+}
+
+func TestHTTP2WebhookTrailer(t *testing.T) {
+	const body = `{"event": "paid"}`
+	keys := keyMap(map[string]*KeyInfo{"key": {Key: "key", Secret: "secret"}})
+	var got string
+	srv := newHTTP2Server(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, b, err := VerifyWebhook(r, keys, time.Minute, 1<<10)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		got = string(b)
+	}))
+
+	tests := []struct {
+		name   string
+		pKey   string
+		status int
+	}{
+		{"trailer", "secret", http.StatusOK},
+		{"wrong secret", "other", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		got = ""
+		r, _ := http.NewRequest(http.MethodPost, srv.URL+"/hooks", nil)
+		SignWebhookTrailer(r, "key", tt.pKey, strings.NewReader(body), WebhookMeta{EventID: "evt"})
+		res, err := srv.Client().Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		if res.StatusCode != tt.status || (tt.status == http.StatusOK && got != body) {
+			t.Errorf("%s: status %d, body %q", tt.name, res.StatusCode, got)
+		}
+	}
+}
+
+func TestRequestTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *http.Request
+		host string
+		uri  string
+	}{
+		{"HTTP/1.1", &http.Request{Host: "API.example.com", RequestURI: "/a?b=c", URL: &url.URL{Path: "/a", RawQuery: "b=c"}}, "api.example.com", "/a?b=c"},
+		// Requests built in process, e.g. by h2c bridges, may only set URL.
+		{"URL only", &http.Request{URL: &url.URL{Host: "api.example.com", Path: "/a", RawQuery: "b=c"}}, "api.example.com", "/a?b=c"},
+		{"no URL", &http.Request{Host: "api.example.com"}, "api.example.com", ""},
+	}
+	for _, tt := range tests {
+		if host := requestHost(tt.r); host != tt.host {
+			t.Errorf("%s: host %q, want %q", tt.name, host, tt.host)
+		}
+		if uri := requestURI(tt.r); uri != tt.uri {
+			t.Errorf("%s: request URI %q, want %q", tt.name, uri, tt.uri)
+		}
+	}
+}
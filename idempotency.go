@@ -0,0 +1,218 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header carrying a request's idempotency key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// CachedResponse is a response recorded for an idempotency key.
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// Request identifies the request answered, its method, path, query and
+	// body digest, so reusing the key for another request is rejected.
+	Request string
+}
+
+// IdempotencyStore stores responses by API key and idempotency key.
+type IdempotencyStore interface {
+	// Get returns the response stored for key.
+	Get(key string) (*CachedResponse, bool)
+	// Reserve reserves key for a request being handled, reporting false
+	// when it's already reserved or has a response.
+	Reserve(key string) bool
+	// Put stores resp for key, releasing its reservation. A nil resp only
+	// releases it, e.g. when the handler panics.
+	Put(key string, resp *CachedResponse)
+}
+
+// WithIdempotency binds the Idempotency-Key header of requests into their
+// signature (see SignIdempotencyKey). Handler answers replays of an API
+// key's idempotency key with the response cached in store, duplicates
+// still being handled with a 409 status, and reuses of the key for another
+// request with a 422 status.
+func WithIdempotency(store IdempotencyStore) Option {
+	return func(v *Validator) {
+		v.idem = store
+	}
+}
+
+func (v *Validator) idempotent(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, ok := FromContext(r.Context())
+		idemKey := r.Header.Get(IdempotencyKeyHeader)
+		if !ok || idemKey == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		key := info.Key + ":" + idemKey
+		sum, err := RequestBodyHash(r)
+		if err != nil {
+			v.writeError(w, v.newError(http.StatusBadRequest, r, "reading body: %s", err))
+			return
+		}
+		req := r.Method + " " + r.URL.EscapedPath() + "?" + v.StripQuery(r.URL.RawQuery) + " " + sum
+
+		if !v.idem.Reserve(key) {
+			// Answered meanwhile, or still being handled.
+			if resp, ok := v.idem.Get(key); ok {
+				v.replayResponse(w, r, resp, req)
+				return
+			}
+			v.writeError(w, v.newError(http.StatusConflict, r, "idempotency key `%s` in use", idemKey))
+			return
+		}
+		var resp *CachedResponse
+		defer func() {
+			v.idem.Put(key, resp)
+		}()
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		resp = &CachedResponse{
+			Status:  rec.status,
+			Header:  w.Header().Clone(),
+			Body:    rec.body.Bytes(),
+			Request: req,
+		}
+	})
+}
+
+// replayResponse writes resp, cached for the request req.
+func (v *Validator) replayResponse(w http.ResponseWriter, r *http.Request, resp *CachedResponse, req string) {
+	if resp.Request != req {
+		v.writeError(w, v.newError(http.StatusUnprocessableEntity, r, "idempotency key `%s` reused for another request", r.Header.Get(IdempotencyKeyHeader)))
+		return
+	}
+	for k, vals := range resp.Header {
+		w.Header()[k] = vals
+	}
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// recorder records the status and body written through it.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore whose responses
+// expire after a TTL.
+type MemoryIdempotencyStore struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu        sync.Mutex
+	responses map[string]*cachedEntry
+	// byExp lists the entries stored, soonest to expire first as they all
+	// expire after the TTL, for expired ones to be dropped without
+	// scanning responses.
+	byExp []keyExp
+}
+
+type keyExp struct {
+	key string
+	exp time.Time
+}
+
+// cachedEntry is a response, or when resp is nil, a reservation.
+type cachedEntry struct {
+	resp    *CachedResponse
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore returns a store keeping responses, and
+// reservations of requests not answered, for ttl.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{ttl: ttl, clock: SystemClock{}, responses: make(map[string]*cachedEntry)}
+}
+
+// WithClock sets the clock responses expire by.
+func (s *MemoryIdempotencyStore) WithClock(c Clock) *MemoryIdempotencyStore {
+	s.clock = c
+	return s
+}
+
+// Get returns the unexpired response stored for key.
+func (s *MemoryIdempotencyStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.responses[key]
+	if !ok || e.resp == nil {
+		return nil, false
+	}
+	if s.clock.Now().After(e.expires) {
+		delete(s.responses, key)
+		return nil, false
+	}
+	return e.resp, true
+}
+
+// Reserve reserves key, unless it's reserved or has an unexpired response.
+func (s *MemoryIdempotencyStore) Reserve(key string) bool {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(now)
+	if e, ok := s.responses[key]; ok && !now.After(e.expires) {
+		return false
+	}
+	s.store(key, &cachedEntry{nil, now.Add(s.ttl)})
+	return true
+}
+
+// Put stores resp for key, dropping expired responses.
+func (s *MemoryIdempotencyStore) Put(key string, resp *CachedResponse) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(now)
+	if resp == nil {
+		delete(s.responses, key)
+		return
+	}
+	s.store(key, &cachedEntry{resp, now.Add(s.ttl)})
+}
+
+func (s *MemoryIdempotencyStore) store(key string, e *cachedEntry) {
+	s.responses[key] = e
+	s.byExp = append(s.byExp, keyExp{key, e.expires})
+}
+
+// expire drops the entries expired at now, those stored again since
+// being left to expire later.
+func (s *MemoryIdempotencyStore) expire(now time.Time) {
+	for len(s.byExp) > 0 && now.After(s.byExp[0].exp) {
+		k := s.byExp[0]
+		if e, ok := s.responses[k.key]; ok && !e.expires.After(k.exp) {
+			delete(s.responses, k.key)
+		}
+		s.byExp = s.byExp[1:]
+	}
+}
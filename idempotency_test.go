@@ -0,0 +1,105 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	var calls atomic.Int32
+	block := make(chan struct{})
+	v := NewValidator(func(key string) (string, int) { return "secret", 300 },
+		WithIdempotency(NewMemoryIdempotencyStore(time.Minute)))
+	h := v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if r.URL.Path == "/slow" {
+			<-block
+		}
+		fmt.Fprint(w, "charge ", n)
+	}))
+	serve := func(path, idemKey, body string) *httptest.ResponseRecorder {
+		u := Sign(http.MethodPost, "key", "secret", "http://example.com"+path, nil, SignIdempotencyKey(idemKey))
+		r := httptest.NewRequest(http.MethodPost, u, strings.NewReader(body))
+		r.Header.Set(IdempotencyKeyHeader, idemKey)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		idemKey string
+		body    string
+		status  int
+		resp    string
+	}{
+		{"first", "/charges", "a", "100", http.StatusOK, "charge 1"},
+		{"replayed", "/charges", "a", "100", http.StatusOK, "charge 1"},
+		{"other key", "/charges", "b", "100", http.StatusOK, "charge 2"},
+		{"other body", "/charges", "a", "999", http.StatusUnprocessableEntity, ""},
+		{"other path", "/refunds", "a", "100", http.StatusUnprocessableEntity, ""},
+	}
+	for _, tt := range tests {
+		w := serve(tt.path, tt.idemKey, tt.body)
+		if w.Code != tt.status || (tt.resp != "" && w.Body.String() != tt.resp) {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, w.Code, w.Body, tt.status, tt.resp)
+		}
+	}
+
+	// Duplicates of a request still being handled are rejected.
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve("/slow", "c", "100")
+	}()
+	for calls.Load() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	if w := serve("/slow", "c", "100"); w.Code != http.StatusConflict {
+		t.Errorf("in flight: status %d, want %d", w.Code, http.StatusConflict)
+	}
+	close(block)
+	if w := <-done; w.Body.String() != "charge 3" {
+		t.Errorf("in flight: %q", w.Body)
+	}
+	if w := serve("/slow", "c", "100"); w.Body.String() != "charge 3" {
+		t.Errorf("answered: %q", w.Body)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("handler called %d times, want 3", n)
+	}
+}
+
+func TestMemoryIdempotencyExpiry(t *testing.T) {
+	clock := newTestClock()
+	s := NewMemoryIdempotencyStore(time.Minute).WithClock(clock)
+	s.Reserve("a")
+	s.Put("a", &CachedResponse{Status: http.StatusOK})
+	clock.now = clock.now.Add(30 * time.Second)
+	s.Reserve("b")
+	s.Put("b", &CachedResponse{Status: http.StatusOK})
+
+	clock.now = clock.now.Add(31 * time.Second)
+	if _, ok := s.Get("a"); ok {
+		t.Error("a: not expired")
+	}
+	if _, ok := s.Get("b"); !ok {
+		t.Error("b: expired")
+	}
+	// Expired entries are dropped by later writes, even when never read.
+	s.Reserve("c")
+	clock.now = clock.now.Add(time.Minute)
+	s.Put("c", nil)
+	if n := len(s.responses); n != 0 {
+		t.Errorf("%d entries left, want 0", n)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// impersonationPrefix starts the API key of impersonation tokens.
+const impersonationPrefix = "imp."
+
+// Impersonation marks requests made by support staff as a customer's key
+// (see MintImpersonation).
+type Impersonation struct {
+	// Key is the impersonated key.
+	Key string `json:"key"`
+	// Staff identifies who minted the token.
+	Staff string `json:"staff"`
+	// Reason is why, e.g. a ticket.
+	Reason  string `json:"reason"`
+	Expires int64  `json:"exp"`
+}
+
+// ImpersonationFunc is called for each validated request made with an
+// impersonation token, to audit it.
+type ImpersonationFunc func(r *http.Request, imp *Impersonation)
+
+// MintImpersonation returns a short-lived API key and secret signing
+// requests as key, for staff to debug a customer's calls without copying
+// its secret. They're derived from master, shared by support tools and
+// validators only, never from key's secret.
+//
+// Validators must be created with WithImpersonation to accept them. opts
+// may set the clock (see SignClock).
+func MintImpersonation(master []byte, key, staff, reason string, ttl time.Duration, opts ...SignOption) (apikey, secret string) {
+	b, _ := json.Marshal(&Impersonation{Key: key, Staff: staff, Reason: reason, Expires: signNow(opts).Add(ttl).Unix()})
+	apikey = impersonationPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return apikey, impersonationSecret(master, apikey)
+}
+
+// WithImpersonation accepts requests signed with impersonation tokens
+// minted with master (see MintImpersonation) until they expire. The
+// request's KeyInfo is that of the impersonated key, with Impersonation
+// set. Each of them is logged, and passed to fn when set.
+func WithImpersonation(master []byte, fn ImpersonationFunc) Option {
+	return func(v *Validator) {
+		v.impersonation = master
+		v.onImpersonated = fn
+	}
+}
+
+func isImpersonationKey(key string) bool {
+	return strings.HasPrefix(key, impersonationPrefix)
+}
+
+// impersonationKey returns the KeyInfo for an impersonation token's API key.
+func (v *Validator) impersonationKey(apikey string) (*KeyInfo, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(apikey, impersonationPrefix))
+	if err != nil {
+		return nil, nil
+	}
+	imp := new(Impersonation)
+	if err := json.Unmarshal(b, imp); err != nil || imp.Key == "" || imp.Staff == "" {
+		return nil, nil
+	}
+	if v.clock.Now().Unix() > imp.Expires {
+		return nil, ErrExpiredToken
+	}
+
+	info, err := v.keyInfo(imp.Key)
+	if err != nil || info == nil {
+		return info, err
+	}
+	// Keys holding only a public key are signed for by their owners only.
+	if info.Secret == "" {
+		return nil, nil
+	}
+	// The token replaces the key's credentials, device proofs included.
+	derived := *info
+	derived.Secret = impersonationSecret(v.impersonation, apikey)
+	derived.Previous = nil
+	derived.PublicKey = nil
+	derived.Device = nil
+	derived.Impersonation = imp
+	derived.apikey = apikey
+	return &derived, nil
+}
+
+// audit records a validated request made with an impersonation token.
+func (v *Validator) audit(r *http.Request, imp *Impersonation) {
+	v.log("hancock: key", KeyAlias(imp.Key), "impersonated by", imp.Staff, "reason", imp.Reason, r.Method, r.URL.Path)
+	if v.onImpersonated != nil {
+		v.onImpersonated(r, imp)
+	}
+}
+
+func impersonationSecret(master []byte, apikey string) string {
+	hash := hmac.New(sha256.New, master)
+	hash.Write([]byte("impersonation:" + apikey))
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// Impersonated returns the impersonation of a validated request made with
+// an impersonation token.
+func Impersonated(ctx context.Context) (*Impersonation, bool) {
+	info, ok := FromContext(ctx)
+	if !ok || info.Impersonation == nil {
+		return nil, false
+	}
+	return info.Impersonation, true
+}
@@ -0,0 +1,68 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImpersonation(t *testing.T) {
+	master := []byte("master")
+	clock := newTestClock()
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	keys := WithKeyInfo(keyMap(map[string]*KeyInfo{
+		"key":    {Key: "key", Secret: "secret", Expires: 300},
+		"public": {Key: "public", PublicKey: pub, Expires: 300},
+	}))
+	mint := func(master []byte, key string, ttl time.Duration) (string, string) {
+		return MintImpersonation(master, key, "staff", "ticket 1", ttl, SignClock(clock))
+	}
+	valid := func() (string, string) { return mint(master, "key", time.Minute) }
+	var audited *Impersonation
+	accept := WithImpersonation(master, func(r *http.Request, imp *Impersonation) { audited = imp })
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		token  func() (string, string)
+		status int
+	}{
+		{"valid", []Option{accept}, valid, http.StatusOK},
+		{"not accepted", nil, valid, http.StatusUnauthorized},
+		{"other master", []Option{accept}, func() (string, string) { return mint([]byte("other"), "key", time.Minute) }, http.StatusUnauthorized},
+		{"expired", []Option{accept}, func() (string, string) { return mint(master, "key", -time.Second) }, http.StatusUnauthorized},
+		{"unknown key", []Option{accept}, func() (string, string) { return mint(master, "none", time.Minute) }, http.StatusUnauthorized},
+		// Keys without a secret are only signed for by their owners.
+		{"public key", []Option{accept}, func() (string, string) { return mint(master, "public", time.Minute) }, http.StatusUnauthorized},
+		{"tampered", []Option{accept}, func() (string, string) {
+			apikey, secret := valid()
+			return strings.Replace(apikey, ".", ".x", 1), secret
+		}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		var imp *Impersonation
+		audited = nil
+		opts := append([]Option{keys, WithClock(clock)}, tt.opts...)
+		v := NewValidator(nil, opts...)
+		h := v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			imp, _ = Impersonated(r.Context())
+		}))
+		apikey, secret := tt.token()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, apikey, secret, "/", nil, SignClock(clock)), nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if tt.status == http.StatusOK && (imp == nil || imp.Key != "key" || imp.Staff != "staff" || audited != imp) {
+			t.Errorf("%s: impersonation %+v, audited %+v", tt.name, imp, audited)
+		}
+	}
+}
@@ -1,4 +1,13 @@
 #!/bin/sh -
 
 go install code.minty.io/hancock
+go install code.minty.io/hancock/blake2
+go install code.minty.io/hancock/chaos
+go install code.minty.io/hancock/compat
+go install code.minty.io/hancock/gateway
+go install code.minty.io/hancock/hancocktest
+go install code.minty.io/hancock/opa
+go install code.minty.io/hancock/redis
 go install code.minty.io/hancock/wrappers
+go install code.minty.io/hancock/cmd/hancock-demo
+go install code.minty.io/hancock/cmd/hancock-difffuzz
@@ -0,0 +1,189 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDeviceKey is returned when provisioning with an invalid device key.
+var ErrDeviceKey = errors.New("hancock: invalid device key")
+
+// IssuedKey is the record kept of an issued API key. It holds the secret's
+// fingerprint, never the secret.
+type IssuedKey struct {
+	Key         string    `json:"key"`
+	Fingerprint string    `json:"fingerprint"`
+	Scopes      []string  `json:"scopes,omitempty"`
+	Device      []byte    `json:"device,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// IssuedKeyStore persists IssuedKey records.
+type IssuedKeyStore interface {
+	Put(k *IssuedKey) error
+	// Get returns the record for key, or nil when there is none.
+	Get(key string) (*IssuedKey, error)
+}
+
+// Issuer mints API key pairs. Secrets are derived from a master secret, so
+// they're handed out once at issuance and never stored; the store only
+// keeps their fingerprints.
+type Issuer struct {
+	master  []byte
+	store   IssuedKeyStore
+	expires int
+	clock   Clock
+}
+
+// NewIssuer returns an Issuer deriving secrets from master. Its keys use
+// expires as their expiration duration (see Validate).
+func NewIssuer(master []byte, store IssuedKeyStore, expires int) *Issuer {
+	return &Issuer{master: master, store: store, expires: expires, clock: SystemClock{}}
+}
+
+// WithClock sets the clock keys are created by.
+func (i *Issuer) WithClock(c Clock) *Issuer {
+	i.clock = c
+	return i
+}
+
+// Issue mints a new key pair granted scopes.
+func (i *Issuer) Issue(scopes []string) (key, secret string, err error) {
+	return i.issue(scopes, nil)
+}
+
+// Provision mints a new key pair granted scopes, bound to the device
+// holding the private key of device (e.g. enrolled at manufacturing).
+// Requests must carry the device's proof besides their signature.
+func (i *Issuer) Provision(device ed25519.PublicKey, scopes []string) (key, secret string, err error) {
+	if len(device) != ed25519.PublicKeySize {
+		return "", "", ErrDeviceKey
+	}
+	return i.issue(scopes, device)
+}
+
+func (i *Issuer) issue(scopes []string, device ed25519.PublicKey) (key, secret string, err error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = base64.RawURLEncoding.EncodeToString(b)
+	secret = i.secret(key)
+	err = i.store.Put(&IssuedKey{
+		Key:         key,
+		Fingerprint: fingerprint(secret),
+		Scopes:      scopes,
+		Device:      device,
+		Created:     i.clock.Now().UTC(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return key, secret, nil
+}
+
+// KeyInfo is a KeyInfoFunc for the issued keys.
+func (i *Issuer) KeyInfo(key string) (*KeyInfo, error) {
+	k, err := i.store.Get(key)
+	if err != nil || k == nil {
+		return nil, err
+	}
+	secret := i.secret(key)
+	if !hmac.Equal([]byte(fingerprint(secret)), []byte(k.Fingerprint)) {
+		// Issued under a different master secret.
+		return nil, nil
+	}
+	return &KeyInfo{Key: key, Secret: secret, Expires: i.expires, Scopes: k.Scopes, Device: k.Device}, nil
+}
+
+// Handler returns a handler minting a key pair for each POST request,
+// granted the comma separated "scopes" form value, and writing it as JSON.
+// The response is the only time the secret is delivered. Keys are bound to
+// the "device" form value, a base64url Ed25519 public key, when it's set.
+//
+// It must be protected, e.g. by a Validator and RequireScopes.
+func (i *Issuer) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var scopes []string
+		if s := r.PostFormValue("scopes"); s != "" {
+			scopes = strings.Split(s, ",")
+		}
+		var device ed25519.PublicKey
+		if d := r.PostFormValue("device"); d != "" {
+			b, err := base64.RawURLEncoding.DecodeString(d)
+			if err != nil || len(b) != ed25519.PublicKeySize {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			device = b
+		}
+		key, secret, err := i.issue(scopes, device)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"key":    key,
+			"secret": secret,
+			"scopes": scopes,
+		})
+	})
+}
+
+func (i *Issuer) secret(key string) string {
+	hash := hmac.New(sha256.New, i.master)
+	hash.Write([]byte("issue:" + key))
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil))
+}
+
+func fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryIssuedKeyStore is an in-memory IssuedKeyStore.
+type MemoryIssuedKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*IssuedKey
+}
+
+// NewMemoryIssuedKeyStore returns an empty MemoryIssuedKeyStore.
+func NewMemoryIssuedKeyStore() *MemoryIssuedKeyStore {
+	return &MemoryIssuedKeyStore{keys: make(map[string]*IssuedKey)}
+}
+
+// Put stores k.
+func (s *MemoryIssuedKeyStore) Put(k *IssuedKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[k.Key] = k
+	return nil
+}
+
+// Get returns the record for key.
+func (s *MemoryIssuedKeyStore) Get(key string) (*IssuedKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[key], nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// JobTokenHeader is the header background workers send job tokens in.
+const JobTokenHeader = "X-Hancock-Job-Token"
+
+// ErrAudience is returned for tokens minted for a different audience.
+var ErrAudience = errors.New("hancock: token audience mismatch")
+
+// JobToken lets a background worker call back into a service on behalf of
+// an API key (and optionally a user of it), limited to a set of scopes.
+type JobToken struct {
+	// Key is the API key the job acts for.
+	Key string `json:"key"`
+	// Subject optionally identifies the user the job acts for.
+	Subject string `json:"sub,omitempty"`
+	// Audience is the only service the token is accepted by.
+	Audience string `json:"aud"`
+	// Scopes are the scopes the job is limited to.
+	Scopes []string `json:"scopes,omitempty"`
+	// Expires is the Unix time the token expires at.
+	Expires int64 `json:"exp"`
+}
+
+// MintJobToken returns a job token for audience, valid for ttl and signed
+// with the private key of key. opts may set the clock (see SignClock).
+func MintJobToken(key, pKey, subject, audience string, scopes []string, ttl time.Duration, opts ...SignOption) string {
+	return signToken("job", pKey, &JobToken{
+		Key:      key,
+		Subject:  subject,
+		Audience: audience,
+		Scopes:   scopes,
+		Expires:  signNow(opts).Add(ttl).Unix(),
+	})
+}
+
+// ValidateJobToken validates a job token minted for audience.
+func ValidateJobToken(token, audience string, keys KeyInfoFunc) (*JobToken, error) {
+	t, _, err := validateJobToken(token, audience, keys, SystemClock{})
+	return t, err
+}
+
+// ValidateJobToken is ValidateJobToken with v's keys and clock.
+func (v *Validator) ValidateJobToken(token, audience string) (*JobToken, error) {
+	t, _, err := validateJobToken(token, audience, v.keys, v.clock)
+	return t, err
+}
+
+func validateJobToken(token, audience string, keys KeyInfoFunc, clock Clock) (*JobToken, *KeyInfo, error) {
+	if keys == nil {
+		return nil, nil, ErrInvalidToken
+	}
+	t := new(JobToken)
+	if err := decodeToken(token, t); err != nil {
+		return nil, nil, err
+	}
+	info, err := keys(t.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info == nil || info.Secret == "" {
+		return nil, nil, ErrInvalidToken
+	}
+	info = keyed(info, t.Key)
+	if err := verifyToken("job", info.Secret, token); err != nil {
+		return nil, nil, err
+	}
+	if t.Audience != audience {
+		return nil, nil, ErrAudience
+	}
+	if clock.Now().Unix() > t.Expires {
+		return nil, nil, ErrExpiredToken
+	}
+	return t, info, nil
+}
+
+// JobHandler returns a handler that validates the job token sent in the
+// JobTokenHeader before invoking h.
+//
+// The request context carries the key's KeyInfo (see FromContext), with its
+// scopes narrowed to those granted by both the key and the token.
+func JobHandler(audience string, keys KeyInfoFunc, h http.Handler) http.Handler {
+	return jobHandler(audience, keys, SystemClock{}, h)
+}
+
+// JobHandler is JobHandler with v's keys and clock.
+func (v *Validator) JobHandler(audience string, h http.Handler) http.Handler {
+	return jobHandler(audience, v.keys, v.clock, h)
+}
+
+func jobHandler(audience string, keys KeyInfoFunc, clock Clock, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, info, err := validateJobToken(r.Header.Get(JobTokenHeader), audience, keys, clock)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		narrowed := *info
+		narrowed.Scopes = narrowScopes(info.Scopes, t.Scopes)
+		h.ServeHTTP(w, r.WithContext(NewContext(r.Context(), &narrowed)))
+	})
+}
@@ -0,0 +1,37 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobTokenClock(t *testing.T) {
+	keys := keyMap(map[string]*KeyInfo{"key": {Key: "key", Secret: "secret"}})
+	clock := newTestClock()
+	v := NewValidator(nil, WithKeyInfo(keys), WithClock(clock))
+	// Minted on the test clock, years behind the system clock.
+	token := MintJobToken("key", "secret", "sub", "aud", nil, time.Minute, SignClock(clock))
+	tests := []struct {
+		name     string
+		audience string
+		after    time.Duration
+		valid    bool
+	}{
+		{"valid", "aud", 0, true},
+		{"other audience", "other", 0, false},
+		{"expired", "aud", 2 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		clock.now = time.Unix(1700000000, 0).Add(tt.after)
+		if _, err := v.ValidateJobToken(token, tt.audience); (err == nil) != tt.valid {
+			t.Errorf("%s: %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+	if _, err := ValidateJobToken(token, "aud", keys); err == nil {
+		t.Error("validated on the system clock")
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// KeyInfo describes an API key.
+type KeyInfo struct {
+	// Key is the public API key.
+	Key string
+	// Secret is the private key requests are signed with.
+	Secret string
+	// Previous are secrets still accepted while clients rotate to Secret.
+	Previous []string
+	// Expires is the expiration duration in seconds (see Validate).
+	Expires int
+	// Policy, when set, overrides Expires. Requests are rejected when its
+	// window is under a second.
+	Policy *ExpirePolicy
+	// Scopes are the authorizations granted to the key.
+	Scopes []string
+	// Tier is the key's customer class, used to label metrics.
+	Tier string
+	// Device is the public key of the device the key is bound to, whose
+	// proof requests must carry (see SignDeviceProof).
+	Device ed25519.PublicKey
+	// PublicKey verifies signatures made with an asymmetric algorithm (see
+	// SignQSKey), e.g. an ed25519.PublicKey. A key may have it only, with
+	// no Secret.
+	PublicKey crypto.PublicKey
+	// Environments are those the key may be used in (see WithEnvironment),
+	// all of them when empty.
+	Environments []string
+	// Hosts are the lowercase hosts the key may be used against, with or
+	// without a port (e.g. "api.example.com" matches any), all of them
+	// when empty.
+	Hosts []string
+	// Flags are the key's feature flags (see Flag), e.g. for beta features.
+	Flags map[string]bool
+	// Attributes are arbitrary values for handlers (see Attribute).
+	Attributes map[string]string
+	// Impersonation is set for requests made with an impersonation token
+	// (see MintImpersonation).
+	Impersonation *Impersonation
+
+	// apikey is the API key signed with, when the key's credentials were
+	// derived from Key's, e.g. a page or impersonation token.
+	apikey string
+}
+
+// signingKey returns the API key info's Secret signs for.
+func (info *KeyInfo) signingKey() string {
+	if info.apikey != "" {
+		return info.apikey
+	}
+	return info.Key
+}
+
+// ExpirePolicy is how long signed requests are accepted for, e.g. a relaxed
+// window for a legacy partner, without Expires' magic numbers.
+type ExpirePolicy struct {
+	// Window is how far a request's timestamp may be from now.
+	Window time.Duration
+	// Grace is accepted past Window, logged, e.g. while a partner fixes
+	// a drifting clock.
+	Grace time.Duration
+	// Skip doesn't check timestamps at all.
+	Skip bool
+	// Disabled doesn't check requests at all, everything is valid.
+	Disabled bool
+}
+
+// Expires returns an ExpirePolicy accepting requests signed within d of now,
+// d being at least a second.
+func Expires(d time.Duration) *ExpirePolicy {
+	return &ExpirePolicy{Window: d}
+}
+
+// NoExpiry returns an ExpirePolicy accepting requests signed at any time.
+func NoExpiry() *ExpirePolicy {
+	return &ExpirePolicy{Skip: true}
+}
+
+// Disabled returns an ExpirePolicy accepting every request, signed or not,
+// e.g. for local development.
+func Disabled() *ExpirePolicy {
+	return &ExpirePolicy{Disabled: true}
+}
+
+// seconds returns p as an expiration duration in seconds (see Validate).
+func (p *ExpirePolicy) seconds() int {
+	switch {
+	case p.Disabled:
+		return -2
+	case p.Skip:
+		return -1
+	}
+	return int((p.Window + p.Grace) / time.Second)
+}
+
+// inGrace reports whether a request signed at ts is only accepted thanks to p's grace.
+func (p *ExpirePolicy) inGrace(ts string, now time.Time) bool {
+	if p.Skip || p.Disabled || p.Grace <= 0 {
+		return false
+	}
+	_, ok := isValidTS(ts, int(p.Window/time.Second), now)
+	return !ok
+}
+
+// KeyInfoFunc returns the KeyInfo for key, or nil when the key is unknown.
+// An error is returned when the keys couldn't be looked up.
+type KeyInfoFunc func(key string) (*KeyInfo, error)
+
+// secrets returns the secrets of info, Secret first. Empty secrets, which
+// anyone could sign or derive secrets with, are left out.
+func (info *KeyInfo) secrets() []string {
+	secrets := make([]string, 0, 1+len(info.Previous))
+	for _, s := range append([]string{info.Secret}, info.Previous...) {
+		if s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+	return secrets
+}
+
+// keyInfo adapts a KeyFunc to a KeyInfoFunc.
+func (fn KeyFunc) keyInfo(key string) (*KeyInfo, error) {
+	pKey, expires := fn(key)
+	if pKey == "" {
+		return nil, nil
+	}
+	return &KeyInfo{Key: key, Secret: pKey, Expires: expires}, nil
+}
+
+// lookupKey looks up key, giving up once r's context is done.
+// A lookup that is given up on keeps running in the background.
+func (v *Validator) lookupKey(r *http.Request, key string) (*KeyInfo, *Error) {
+	type result struct {
+		info *KeyInfo
+		err  error
+	}
+	var res result
+	if done := r.Context().Done(); done == nil {
+		res.info, res.err = v.findKey(key)
+	} else {
+		c := make(chan result, 1)
+		go func() {
+			info, err := v.findKey(key)
+			c <- result{info, err}
+		}()
+		select {
+		case res = <-c:
+		case <-done:
+			return nil, ctxError(r)
+		}
+	}
+
+	if errors.Is(res.err, ErrExpiredToken) {
+		return nil, v.newError(http.StatusUnauthorized, r, "expired key `%s`", KeyAlias(key))
+	} else if res.err != nil {
+		return nil, v.newError(http.StatusServiceUnavailable, r, "key lookup failed: %s", res.err)
+	}
+	if res.info == nil || (res.info.Secret == "" && res.info.PublicKey == nil) {
+		return nil, v.newError(http.StatusUnauthorized, r, "unknown key `%s`", KeyAlias(key))
+	}
+	DefaultAliases.Remember(key)
+	return keyed(res.info, key), nil
+}
+
+// keyed returns info, or when its Key is unset a copy of it naming key, so
+// per key state, e.g. rate limit buckets, isn't shared by every key whose
+// KeyInfoFunc leaves it out.
+func keyed(info *KeyInfo, key string) *KeyInfo {
+	if info.Key != "" {
+		return info
+	}
+	named := *info
+	named.Key = key
+	return &named
+}
+
+// findKey returns the KeyInfo for key, deriving it for derived keys such as
+// page tokens.
+func (v *Validator) findKey(key string) (*KeyInfo, error) {
+	if v.keys == nil {
+		return nil, nil
+	}
+	if v.pageTokens && isPageKey(key) {
+		return v.pageKey(key)
+	}
+	if v.impersonation != nil && isImpersonationKey(key) {
+		return v.impersonationKey(key)
+	}
+	return v.keyInfo(key)
+}
+
+type contextKey int
+
+const keyInfoKey contextKey = 0
+
+// NewContext returns a copy of ctx carrying info.
+func NewContext(ctx context.Context, info *KeyInfo) context.Context {
+	return context.WithValue(ctx, keyInfoKey, info)
+}
+
+// FromContext returns the KeyInfo of a validated request.
+func FromContext(ctx context.Context) (*KeyInfo, bool) {
+	info, ok := ctx.Value(keyInfoKey).(*KeyInfo)
+	return info, ok && info != nil
+}
+
+// Flag reports whether the feature flag name is set for the key of a
+// validated request.
+func Flag(ctx context.Context, name string) bool {
+	info, ok := FromContext(ctx)
+	return ok && info.Flags[name]
+}
+
+// Attribute returns the attribute name of the key of a validated request.
+func Attribute(ctx context.Context, name string) (string, bool) {
+	info, ok := FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	v, ok := info.Attributes[name]
+	return v, ok
+}
@@ -0,0 +1,38 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/netip"
+)
+
+// WithLegacyNetworks lets unsigned requests from remote addresses within
+// prefixes (see ParsePrefixes) through Handler unvalidated, logging and
+// counting them in Status, while migrating internal callers last. Signed
+// requests from them are still validated.
+func WithLegacyNetworks(prefixes ...netip.Prefix) Option {
+	return func(v *Validator) {
+		v.legacy = append(v.legacy, prefixes...)
+	}
+}
+
+// isLegacy reports whether r is an unsigned request from a legacy network.
+func (v *Validator) isLegacy(r *http.Request) bool {
+	if len(v.legacy) == 0 {
+		return false
+	}
+	q := r.URL.Query()
+	if q.Get(v.params.APIKey) != "" || q.Get(v.params.Signature) != "" {
+		return false
+	}
+	a, err := RemoteAddr(r)
+	if err != nil || !matchAddr(v.legacy, a) {
+		return false
+	}
+	v.stats.legacy.Add(1)
+	v.log("hancock: unsigned legacy request from", a, r.Method, r.URL.Path)
+	return true
+}
@@ -0,0 +1,43 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestLegacyNetworks(t *testing.T) {
+	rejectAll := NewControls()
+	rejectAll.SetMode(RejectAll)
+	legacy := WithLegacyNetworks(netip.MustParsePrefix("192.0.2.0/24"))
+	tests := []struct {
+		name   string
+		opts   []Option
+		remote string
+		url    string
+		status int
+	}{
+		{"unsigned legacy", []Option{legacy}, "192.0.2.1:1234", "/", http.StatusOK},
+		{"unsigned", []Option{legacy}, "198.51.100.1:1234", "/", http.StatusUnauthorized},
+		{"signed legacy", []Option{legacy}, "192.0.2.1:1234", Sign(http.MethodGet, "key", "other", "/", nil), http.StatusUnauthorized},
+		{"kill switch", []Option{legacy, WithControls(rejectAll)}, "192.0.2.1:1234", "/", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		v := NewValidator(nil, append([]Option{WithKeyInfo(keyMap(map[string]*KeyInfo{
+			"key": {Key: "key", Secret: "secret", Expires: 300},
+		}))}, tt.opts...)...)
+		h := v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		r.RemoteAddr = tt.remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+}
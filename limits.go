@@ -0,0 +1,73 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Limits bound the size of request URLs, checked before any parsing or
+// signature work. Zero fields aren't limited.
+type Limits struct {
+	// MaxURL is the maximum length of the request URI; longer URIs get a 414.
+	MaxURL int
+	// MaxParams is the maximum number of query parameters.
+	MaxParams int
+	// MaxValue is the maximum length of a single, still escaped, parameter.
+	MaxValue int
+}
+
+// WithLimits rejects requests whose URLs exceed l.
+func WithLimits(l Limits) Option {
+	return func(v *Validator) {
+		v.limits = l
+	}
+}
+
+func (v *Validator) checkLimits(r *http.Request) *Error {
+	l := v.limits
+	if l == (Limits{}) {
+		return nil
+	}
+	if l.MaxURL > 0 {
+		n := len(r.RequestURI)
+		if n == 0 {
+			n = len(r.URL.EscapedPath()) + len(r.URL.RawQuery) + 1
+		}
+		if n > l.MaxURL {
+			return limitError(http.StatusRequestURITooLong, r, fmt.Sprintf("URL length exceeds %d", l.MaxURL))
+		}
+	}
+	if l.MaxParams > 0 && strings.Count(r.URL.RawQuery, "&")+1 > l.MaxParams {
+		return limitError(http.StatusBadRequest, r, fmt.Sprintf("parameter count exceeds %d", l.MaxParams))
+	}
+	if l.MaxValue > 0 {
+		for q := r.URL.RawQuery; q != ""; {
+			var pair string
+			pair, q, _ = strings.Cut(q, "&")
+			if len(pair) > l.MaxValue {
+				return limitError(http.StatusBadRequest, r, fmt.Sprintf("parameter length exceeds %d", l.MaxValue))
+			}
+		}
+	}
+	return nil
+}
+
+// limitError is newError for oversized requests, leaving out the query and
+// headers rather than parsing and copying them.
+func limitError(status int, r *http.Request, msg string) *Error {
+	return &Error{
+		Status:  status,
+		Message: msg,
+		Request: RequestInfo{
+			Host:       requestHost(r),
+			Proto:      r.Proto,
+			RemoteAddr: remoteAddrString(r),
+			RequestURI: r.URL.EscapedPath(),
+		},
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(v *Validator, r *http.Request) int
+	}{
+		{"Validate", func(v *Validator, r *http.Request) int {
+			_, err := v.Validate(r)
+			return status(err)
+		}},
+		{"ValidateClaims", func(v *Validator, r *http.Request) int {
+			_, _, err := v.ValidateClaims(r)
+			return status(err)
+		}},
+		{"Handler", func(v *Validator, r *http.Request) int {
+			w := httptest.NewRecorder()
+			v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+			return w.Code
+		}},
+	}
+	for _, tt := range tests {
+		v := NewValidator(nil, WithKeyInfo(keyMap(nil)), WithLimits(Limits{MaxURL: 64}))
+		r := httptest.NewRequest(http.MethodGet, "/?q="+strings.Repeat("a", 64), nil)
+		if got := tt.check(v, r); got != http.StatusRequestURITooLong {
+			t.Errorf("%s: status %d, want %d", tt.name, got, http.StatusRequestURITooLong)
+		}
+		// Limits are checked, and failures counted, once.
+		if n := v.Status().Failed; n != 1 {
+			t.Errorf("%s: %d failures counted, want 1", tt.name, n)
+		}
+	}
+}
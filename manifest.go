@@ -0,0 +1,98 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+var (
+	// ErrManifestSignature is returned when a manifest's signature doesn't match.
+	ErrManifestSignature = errors.New("hancock: manifest signature mismatch")
+	// ErrManifestFile is returned for files missing from a manifest.
+	ErrManifestFile = errors.New("hancock: file not in manifest")
+	// ErrFileDigest is returned when a file doesn't match its manifest digest.
+	ErrFileDigest = errors.New("hancock: file digest mismatch")
+)
+
+// Manifest is a signed list of files and their SHA-256 digests (hex encoded,
+// as printed by sha256sum).
+type Manifest struct {
+	APIKey string            `json:"apikey"`
+	TS     int64             `json:"ts"`
+	Files  map[string]string `json:"files"`
+	Data   string            `json:"data"`
+}
+
+// FileDigest returns the hex encoded SHA-256 digest of r.
+func FileDigest(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SignManifest returns a manifest of files (path → digest) signed with pKey.
+// opts may set the clock (see SignClock).
+func SignManifest(key, pKey string, files map[string]string, opts ...SignOption) *Manifest {
+	m := &Manifest{
+		APIKey: key,
+		TS:     signNow(opts).UTC().Unix(),
+		Files:  make(map[string]string, len(files)),
+	}
+	for p, d := range files {
+		m.Files[p] = d
+	}
+	m.Data = m.signature(pKey)
+	return m
+}
+
+// Verify checks the manifest's signature.
+func (m *Manifest) Verify(pKey string) error {
+	if !hmac.Equal([]byte(m.signature(pKey)), []byte(m.Data)) {
+		return ErrManifestSignature
+	}
+	return nil
+}
+
+// VerifyFile checks that the contents of r match the digest listed for path.
+// The manifest itself should already have been checked with Verify.
+func (m *Manifest) VerifyFile(path string, r io.Reader) error {
+	want, ok := m.Files[path]
+	if !ok {
+		return ErrManifestFile
+	}
+	got, err := FileDigest(r)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(got), []byte(want)) {
+		return ErrFileDigest
+	}
+	return nil
+}
+
+func (m *Manifest) signature(pKey string) string {
+	paths := make([]string, 0, len(m.Files))
+	for p := range m.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	hash := hmac.New(sha256.New, []byte(pKey))
+	fmt.Fprintf(hash, "manifest:%s:%d", m.APIKey, m.TS)
+	for _, p := range paths {
+		fmt.Fprintf(hash, "\n%s %s", m.Files[p], p)
+	}
+	return base64.URLEncoding.EncodeToString(hash.Sum(nil))
+}
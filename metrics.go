@@ -0,0 +1,89 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"time"
+)
+
+// Stages of a request reported to Metrics.
+const (
+	StageValidate = "validate"
+	StageHandler  = "handler"
+)
+
+// Measurement is the outcome of one stage of a request.
+type Measurement struct {
+	// Route is the matched SignedMux (or HandleSigned) pattern, if any.
+	Route string
+	// Tier is the Tier of the request's key, once validated.
+	Tier string
+	// Stage is StageValidate or StageHandler.
+	Stage string
+	// Status is the response status; 200 for passed validations.
+	Status   int
+	Duration time.Duration
+}
+
+// Metrics records measurements, e.g. into latency histograms labeled by
+// route and tier.
+type Metrics interface {
+	Observe(m Measurement)
+}
+
+// WithMetrics reports the validation and handler stages of each request
+// handled by Handler to m.
+func WithMetrics(m Metrics) Option {
+	return func(v *Validator) {
+		v.metrics = m
+	}
+}
+
+func withRoute(pattern string) Option {
+	return func(v *Validator) {
+		v.route = pattern
+	}
+}
+
+func (v *Validator) measured(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		r, ok := v.admit(sw, r)
+		m := Measurement{Route: v.route, Stage: StageValidate, Status: sw.status, Duration: time.Since(start)}
+		if ok {
+			if info, ok := FromContext(r.Context()); ok {
+				m.Tier = info.Tier
+			}
+		}
+		v.metrics.Observe(m)
+		if !ok {
+			return
+		}
+
+		start = time.Now()
+		h.ServeHTTP(sw, r)
+		m.Stage, m.Status, m.Duration = StageHandler, sw.status, time.Since(start)
+		v.metrics.Observe(m)
+	})
+}
+
+// statusWriter records the status written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the underlying ResponseWriter, so http.ResponseController
+// can flush, hijack and set deadlines through statusWriter.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
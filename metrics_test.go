@@ -0,0 +1,50 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type nopMetrics struct{}
+
+func (nopMetrics) Observe(m Measurement) {}
+
+func TestResponseController(t *testing.T) {
+	var metrics nopMetrics
+	idem := WithIdempotency(NewMemoryIdempotencyStore(time.Minute))
+	tests := []struct {
+		name string
+		opts []Option
+		idem bool
+	}{
+		{"metrics", []Option{WithMetrics(metrics)}, false},
+		{"idempotency", []Option{idem}, true},
+		{"both", []Option{WithMetrics(metrics), idem}, true},
+	}
+	for _, tt := range tests {
+		v := NewValidator(func(key string) (string, int) { return "secret", 300 }, tt.opts...)
+		var err error
+		h := v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err = http.NewResponseController(w).Flush()
+		}))
+		var signOpts []SignOption
+		if tt.idem {
+			signOpts = append(signOpts, SignIdempotencyKey(tt.name))
+		}
+		r := httptest.NewRequest(http.MethodPost, Sign(http.MethodPost, "key", "secret", "http://example.com/", nil, signOpts...), nil)
+		if tt.idem {
+			r.Header.Set(IdempotencyKeyHeader, tt.name)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if err != nil || !w.Flushed {
+			t.Errorf("%s: flushed %v, %v", tt.name, w.Flushed, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import "net/http"
+
+// SignedMux is a request multiplexer whose routes are validated by a
+// Validator, and rate limited when given a RateLimiter.
+//
+// Patterns follow http.ServeMux, including methods and wildcards
+// ("GET /reports/{id}").
+type SignedMux struct {
+	mux     *http.ServeMux
+	v       *Validator
+	limiter RateLimiter
+}
+
+// RouteOption configures a signed route.
+type RouteOption func(*route)
+
+type route struct {
+	cost       int
+	opts       []Option
+	pathValues []string
+}
+
+// Cost sets the weight a route consumes from the key's rate limit budget,
+// e.g. 10 for a heavy report and 1 (the default) for a cheap lookup.
+func Cost(n int) RouteOption {
+	return func(rt *route) {
+		rt.cost = n
+	}
+}
+
+// Options applies Validator options to a single route (see Validator.With).
+func Options(opts ...Option) RouteOption {
+	return func(rt *route) {
+		rt.opts = append(rt.opts, opts...)
+	}
+}
+
+// PathValues binds the named pattern wildcards to the signature. Requests
+// must carry a signed query parameter of the same name matching each
+// wildcard's value (see http.Request.PathValue), e.g. a request for
+// "/reports/{id}" signed with "id=42" is only valid for "/reports/42".
+func PathValues(names ...string) RouteOption {
+	return func(rt *route) {
+		rt.pathValues = append(rt.pathValues, names...)
+	}
+}
+
+// NewSignedMux returns a SignedMux validating with v.
+// limiter may be nil to skip rate limiting.
+func NewSignedMux(v *Validator, limiter RateLimiter) *SignedMux {
+	return &SignedMux{mux: http.NewServeMux(), v: v, limiter: limiter}
+}
+
+// Handle registers h for pattern.
+func (m *SignedMux) Handle(pattern string, h http.Handler, opts ...RouteOption) {
+	m.mux.Handle(pattern, signedRoute(pattern, m.v, m.limiter, h, opts))
+}
+
+// HandleFunc registers fn for pattern.
+func (m *SignedMux) HandleFunc(pattern string, fn func(http.ResponseWriter, *http.Request), opts ...RouteOption) {
+	m.Handle(pattern, http.HandlerFunc(fn), opts...)
+}
+
+// ServeHTTP dispatches r to the handler registered for its pattern.
+func (m *SignedMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// HandleSigned registers h for pattern on a standard http.ServeMux,
+// validated by v.
+func HandleSigned(mux *http.ServeMux, pattern string, v *Validator, h http.Handler, opts ...RouteOption) {
+	mux.Handle(pattern, signedRoute(pattern, v, nil, h, opts))
+}
+
+func signedRoute(pattern string, v *Validator, limiter RateLimiter, h http.Handler, opts []RouteOption) http.Handler {
+	rt := route{cost: 1}
+	for _, opt := range opts {
+		opt(&rt)
+	}
+	if limiter != nil {
+		h = RateLimit(limiter, rt.cost)(h)
+	}
+	if len(rt.pathValues) > 0 {
+		h = boundPathValues(rt.pathValues, h)
+	}
+	v = v.With(append(rt.opts, withRoute(pattern))...)
+	return v.Handler(h)
+}
+
+// boundPathValues responds with 403 unless each named wildcard matches the
+// signed query parameter of the same name.
+func boundPathValues(names []string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		for _, n := range names {
+			if vals := q[n]; len(vals) != 1 || vals[0] != r.PathValue(n) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,72 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RemoteAddr returns the address of r's client, without its port or zone,
+// and with IPv4-mapped IPv6 addresses unmapped.
+func RemoteAddr(r *http.Request) (netip.Addr, error) {
+	return parseAddr(r.RemoteAddr)
+}
+
+func parseAddr(s string) (netip.Addr, error) {
+	var a netip.Addr
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		a = ap.Addr()
+	} else if a, err = netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")); err != nil {
+		return netip.Addr{}, err
+	}
+	return a.WithZone("").Unmap(), nil
+}
+
+// remoteAddrString is RemoteAddr as a string, or the raw RemoteAddr when it
+// can't be parsed.
+func remoteAddrString(r *http.Request) string {
+	if a, err := RemoteAddr(r); err == nil {
+		return a.String()
+	}
+	return r.RemoteAddr
+}
+
+// ParsePrefixes parses CIDR prefixes, or single addresses, for matching
+// remote addresses. IPv4-mapped IPv6 prefixes are unmapped.
+func ParsePrefixes(s ...string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(s))
+	for _, str := range s {
+		var p netip.Prefix
+		if strings.Contains(str, "/") {
+			var err error
+			if p, err = netip.ParsePrefix(str); err != nil {
+				return nil, err
+			}
+		} else {
+			a, err := parseAddr(str)
+			if err != nil {
+				return nil, err
+			}
+			p = netip.PrefixFrom(a, a.BitLen())
+		}
+		if a := p.Addr(); a.Is4In6() && p.Bits() >= 96 {
+			p = netip.PrefixFrom(a.Unmap(), p.Bits()-96)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// matchAddr reports whether a is within any of prefixes.
+func matchAddr(prefixes []netip.Prefix, a netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
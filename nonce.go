@@ -0,0 +1,67 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WithReplayStore makes signed requests single-use: each must carry a
+// nonce (see SignNonce), recorded in s once its signature matches, and
+// requests reusing a nonce of the same key are rejected. The timestamp
+// window alone allows replays within it.
+func WithReplayStore(s ReplayStore) Option {
+	return func(v *Validator) {
+		v.replay = s
+	}
+}
+
+// SignNonce signs with a random nonce, for validators recording nonces
+// (see WithReplayStore).
+func SignNonce() SignOption {
+	return func(c *signConfig) {
+		b := make([]byte, 16)
+		rand.Read(b)
+		c.nonce = base64.RawURLEncoding.EncodeToString(b)
+	}
+}
+
+// acceptedUntil returns when a request signed at ts, not before nbf and
+// expiring at exp, Unix times when set, stops being accepted with
+// expireSeconds, the zero Time when its timestamps aren't checked.
+func acceptedUntil(ts, nbf, exp string, expireSeconds int) time.Time {
+	if expireSeconds < 0 {
+		return time.Time{}
+	}
+	var until int64
+	for _, s := range []string{ts, nbf} {
+		if t, err := strconv.ParseInt(s, 10, 64); err == nil && t+int64(expireSeconds) > until {
+			until = t + int64(expireSeconds)
+		}
+	}
+	if e, err := strconv.ParseInt(exp, 10, 64); err == nil && e > until {
+		until = e
+	}
+	return time.Unix(until, 0)
+}
+
+// checkNonce records r's nonce until the request stops being accepted, when
+// v has a replay store.
+func (v *Validator) checkNonce(r *http.Request, key, nonce string, until time.Time) *Error {
+	if v.replay == nil {
+		return nil
+	}
+	if nonce == "" {
+		return v.newError(http.StatusBadRequest, r, "missing nonce")
+	}
+	if v.replay.Seen(key+":"+nonce, v.clock.Now(), until) {
+		return v.newError(http.StatusUnauthorized, r, "nonce replayed")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReplayStore(t *testing.T) {
+	type step struct {
+		after  time.Duration // since signing
+		status int
+	}
+	tests := []struct {
+		name  string
+		opts  []SignOption
+		steps []step
+	}{
+		{"single use", []SignOption{SignNonce()}, []step{{0, 0}, {0, http.StatusUnauthorized}, {30 * time.Second, http.StatusUnauthorized}}},
+		{"missing nonce", nil, []step{{0, http.StatusBadRequest}}},
+		{"expired", []SignOption{SignNonce()}, []step{{0, 0}, {2 * time.Minute, http.StatusNotAcceptable}}},
+		// Kept until the signed expiry, past the validator's window.
+		{"signed expiry", []SignOption{SignNonce(), SignExpires(10 * time.Minute)}, []step{{0, 0}, {5 * time.Minute, http.StatusUnauthorized}, {11 * time.Minute, http.StatusNotAcceptable}}},
+		{"not before", []SignOption{SignNonce(), SignNotBefore(newTestClock().now.Add(5 * time.Minute))}, []step{{5 * time.Minute, 0}, {6 * time.Minute, http.StatusUnauthorized}}},
+	}
+	for _, tt := range tests {
+		clock := newTestClock()
+		store := NewMemoryReplayStore(0)
+		v := NewValidator(func(key string) (string, int) { return "secret", 60 },
+			WithClock(clock), WithReplayStore(store))
+		u := Sign(http.MethodGet, "key", "secret", "/", nil, append(tt.opts, SignClock(clock))...)
+		start := clock.now
+		for i, s := range tt.steps {
+			clock.now = start.Add(s.after)
+			if _, err := v.Validate(httptest.NewRequest(http.MethodGet, u, nil)); status(err) != s.status {
+				t.Errorf("%s: step %d: status %d, want %d (%v)", tt.name, i, status(err), s.status, err)
+			}
+		}
+	}
+}
+
+func TestMemoryReplayStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryReplayStore(0)
+	tests := []struct {
+		name  string
+		nonce string
+		now   time.Time
+		exp   time.Time
+		seen  bool
+	}{
+		{"new", "a", now, now.Add(time.Minute), false},
+		{"replayed", "a", now.Add(30 * time.Second), now.Add(time.Minute), true},
+		{"expired", "a", now.Add(2 * time.Minute), now.Add(3 * time.Minute), false},
+		{"forever", "b", now, time.Time{}, false},
+		{"forever replayed", "b", now.Add(1000 * time.Hour), time.Time{}, true},
+	}
+	for _, tt := range tests {
+		if seen := s.Seen(tt.nonce, tt.now, tt.exp); seen != tt.seen {
+			t.Errorf("%s: seen %v, want %v", tt.name, seen, tt.seen)
+		}
+	}
+}
+
+func TestMemoryReplayStoreFull(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name  string
+		evict bool
+		seen  []bool // of nonces a, b, c then a again
+	}{
+		// Full stores reject new nonces until recorded ones expire.
+		{"fail closed", false, []bool{false, false, true, true}},
+		{"evict", true, []bool{false, false, false, false}},
+	}
+	for _, tt := range tests {
+		s := NewMemoryReplayStore(2)
+		s.Evict = tt.evict
+		for i, nonce := range []string{"a", "b", "c", "a"} {
+			exp := now.Add(time.Duration(i+1) * time.Minute)
+			if seen := s.Seen(nonce, now, exp); seen != tt.seen[i] {
+				t.Errorf("%s: %s: seen %v, want %v", tt.name, nonce, seen, tt.seen[i])
+			}
+		}
+		if s.Seen("d", now.Add(time.Hour), now.Add(2*time.Hour)) {
+			t.Errorf("%s: nonce rejected once others expired", tt.name)
+		}
+	}
+}
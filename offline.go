@@ -0,0 +1,171 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// OfflineKey is a key's verifier in an OfflineBundle.
+type OfflineKey struct {
+	Key     string   `json:"key"`
+	Secrets []string `json:"secrets,omitempty"`
+	Expires int      `json:"expires"`
+	Scopes  []string `json:"scopes,omitempty"`
+	Tier    string   `json:"tier,omitempty"`
+	Device  []byte   `json:"device,omitempty"`
+	// PublicKey is the PKIX encoding of KeyInfo.PublicKey.
+	PublicKey []byte `json:"publicKey,omitempty"`
+}
+
+// OfflineBundle is a snapshot of keys and revocations, for validators on
+// devices that can't reach a key store. It holds secrets derived for its
+// audience (see OfflineSecret), never the keys' secrets, so a compromised
+// device exposes only what it could already verify. Keys signing with an
+// asymmetric algorithm are best suited, their bundles hold no secrets.
+type OfflineBundle struct {
+	Audience string       `json:"aud"`
+	Created  int64        `json:"iat"`
+	Expires  int64        `json:"exp"`
+	Keys     []OfflineKey `json:"keys"`
+	Revoked  []string     `json:"revoked,omitempty"`
+	clock    Clock
+	index    map[string]*OfflineKey
+}
+
+// OfflineSecret returns the secret clients sign with for validators using
+// an OfflineBundle of audience.
+func OfflineSecret(pKey, audience string) string {
+	hash := hmac.New(sha256.New, []byte(pKey))
+	hash.Write([]byte("offline:" + audience))
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// NewOfflineBundle returns a bundle for audience of keys, valid for ttl.
+// Revoked keys are left out of it and listed as revoked, so devices merging
+// bundles drop them. opts may set the clock (see SignClock).
+func NewOfflineBundle(audience string, keys []*KeyInfo, revoked []string, ttl time.Duration, opts ...SignOption) (*OfflineBundle, error) {
+	now := signNow(opts)
+	b := &OfflineBundle{
+		Audience: audience,
+		Created:  now.Unix(),
+		Expires:  now.Add(ttl).Unix(),
+		Revoked:  revoked,
+	}
+	for _, info := range keys {
+		if info == nil || (info.Secret == "" && info.PublicKey == nil) || contains(revoked, info.Key) {
+			continue
+		}
+		k := OfflineKey{
+			Key:     info.Key,
+			Expires: info.Expires,
+			Scopes:  info.Scopes,
+			Tier:    info.Tier,
+			Device:  info.Device,
+		}
+		if info.Secret != "" {
+			for _, s := range info.secrets() {
+				k.Secrets = append(k.Secrets, OfflineSecret(s, audience))
+			}
+		}
+		if info.PublicKey != nil {
+			pub, err := x509.MarshalPKIXPublicKey(info.PublicKey)
+			if err != nil {
+				return nil, err
+			}
+			k.PublicKey = pub
+		}
+		b.Keys = append(b.Keys, k)
+	}
+	b.reindex()
+	return b, nil
+}
+
+// Seal returns the bundle as a token signed with the Ed25519 key priv, to
+// ship to devices. Devices verify it with the public key alone (see
+// OpenOfflineBundle), so none can seal bundles of its own.
+func (b *OfflineBundle) Seal(priv ed25519.PrivateKey) (string, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return "", ErrInvalidToken
+	}
+	j, err := json.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(j)
+	sig := ed25519.Sign(priv, []byte("offline:"+payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// OpenOfflineBundle verifies a bundle sealed with the private key of pub,
+// and decodes it.
+func OpenOfflineBundle(token string, pub ed25519.PublicKey) (*OfflineBundle, error) {
+	payload, s, ok := strings.Cut(token, ".")
+	sig, err := base64.RawURLEncoding.DecodeString(s)
+	if !ok || err != nil || len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, []byte("offline:"+payload), sig) {
+		return nil, ErrInvalidToken
+	}
+	b := new(OfflineBundle)
+	if err := decodeToken(token, b); err != nil {
+		return nil, err
+	}
+	b.reindex()
+	return b, nil
+}
+
+func (b *OfflineBundle) reindex() {
+	b.index = make(map[string]*OfflineKey, len(b.Keys))
+	for i := range b.Keys {
+		b.index[b.Keys[i].Key] = &b.Keys[i]
+	}
+	for _, k := range b.Revoked {
+		delete(b.index, k)
+	}
+}
+
+// WithClock sets the clock the bundle's expiration is checked against, for
+// devices whose time comes from elsewhere (e.g. GPS, or a signed time source).
+func (b *OfflineBundle) WithClock(c Clock) *OfflineBundle {
+	b.clock = c
+	return b
+}
+
+// KeyInfo is a KeyInfoFunc for the bundle's keys. No key is accepted once
+// the bundle has expired, so a device left without updates stops accepting
+// requests.
+func (b *OfflineBundle) KeyInfo(key string) (*KeyInfo, error) {
+	if clockOr(b.clock).Now().Unix() > b.Expires {
+		return nil, ErrExpiredToken
+	}
+	k, ok := b.index[key]
+	if !ok {
+		return nil, nil
+	}
+	info := &KeyInfo{
+		Key:     k.Key,
+		Expires: k.Expires,
+		Scopes:  k.Scopes,
+		Tier:    k.Tier,
+		Device:  k.Device,
+	}
+	if len(k.Secrets) > 0 {
+		info.Secret, info.Previous = k.Secrets[0], k.Secrets[1:]
+	}
+	if len(k.PublicKey) > 0 {
+		pub, err := x509.ParsePKIXPublicKey(k.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		info.PublicKey = pub
+	}
+	return info, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOfflineBundleSeal(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	b, err := NewOfflineBundle("device", []*KeyInfo{{Key: "key", Secret: "secret"}}, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := b.Seal(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		pub   ed25519.PublicKey
+		ok    bool
+	}{
+		{"sealed", sealed, pub, true},
+		{"other key", sealed, other, false},
+		{"no key", sealed, nil, false},
+		{"tampered", strings.Replace(sealed, ".", "x.", 1), pub, false},
+		{"unsigned", strings.Split(sealed, ".")[0], pub, false},
+	}
+	for _, tt := range tests {
+		opened, err := OpenOfflineBundle(tt.token, tt.pub)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v", tt.name, err)
+			continue
+		}
+		if tt.ok {
+			if info, _ := opened.KeyInfo("key"); info == nil || info.Secret != OfflineSecret("secret", "device") {
+				t.Errorf("%s: key %+v", tt.name, info)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package opa lets an Open Policy Agent policy make the final allow or deny
+// decision on requests validated by hancock (see hancock.WithAuthorizer),
+// so org policies govern hancock-protected routes without forking the
+// handler.
+//
+// Policies are queried through OPA's REST API:
+//
+//	v := hancock.NewValidator(keyFn, hancock.WithAuthorizer(opa.Authorizer(&opa.Client{
+//		URL: "http://localhost:8181/v1/data/hancock/authz",
+//	})))
+//
+// or evaluated in process by any Evaluator, e.g. wrapping a prepared Rego
+// query, without this package depending on OPA.
+//
+// Policies return either a boolean or an object with allow, reason and
+// obligations, the input being an Input.
+package opa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"code.minty.io/hancock"
+)
+
+// Input is the document policies are evaluated against, `input` in Rego.
+type Input struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Query includes the signing parameters.
+	Query      map[string][]string `json:"query"`
+	RemoteAddr string              `json:"remoteAddr"`
+
+	Key        string            `json:"key"`
+	Scopes     []string          `json:"scopes"`
+	Tier       string            `json:"tier"`
+	Attributes map[string]string `json:"attributes"`
+	Claims     map[string]string `json:"claims"`
+}
+
+// Result is a policy's decision.
+type Result struct {
+	Allow       bool     `json:"allow"`
+	Reason      string   `json:"reason"`
+	Obligations []string `json:"obligations"`
+}
+
+// UnmarshalJSON decodes a boolean or an object result.
+func (res *Result) UnmarshalJSON(b []byte) error {
+	var allow bool
+	if err := json.Unmarshal(b, &allow); err == nil {
+		*res = Result{Allow: allow}
+		return nil
+	}
+	type result Result
+	return json.Unmarshal(b, (*result)(res))
+}
+
+// Evaluator evaluates a policy.
+type Evaluator interface {
+	Eval(ctx context.Context, in *Input) (*Result, error)
+}
+
+// EvaluatorFunc is a function Evaluator.
+type EvaluatorFunc func(ctx context.Context, in *Input) (*Result, error)
+
+// Eval calls fn.
+func (fn EvaluatorFunc) Eval(ctx context.Context, in *Input) (*Result, error) {
+	return fn(ctx, in)
+}
+
+var errUndefined = errors.New("opa: undefined policy result")
+
+// Client evaluates the policy at URL, a Data API document, e.g.
+// "http://localhost:8181/v1/data/hancock/authz".
+type Client struct {
+	URL string
+	// HTTPClient is http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// Eval queries the policy with in.
+func (c *Client) Eval(ctx context.Context, in *Input) (*Result, error) {
+	b, err := json.Marshal(struct {
+		Input *Input `json:"input"`
+	}{in})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, res.Body)
+		return nil, fmt.Errorf("opa: %s", res.Status)
+	}
+	var out struct {
+		Result *Result `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("opa: decoding result: %w", err)
+	}
+	if out.Result == nil {
+		return nil, errUndefined
+	}
+	return out.Result, nil
+}
+
+// Authorizer returns a hancock.Authorizer deferring to e. Requests are
+// denied when e fails, e.g. when OPA can't be reached.
+func Authorizer(e Evaluator) hancock.Authorizer {
+	return hancock.AuthorizerFunc(func(r *http.Request, info *hancock.KeyInfo) hancock.Decision {
+		res, err := e.Eval(r.Context(), NewInput(r, info))
+		if err != nil {
+			return hancock.Deny("policy unavailable")
+		}
+		if !res.Allow {
+			return hancock.Deny(res.Reason)
+		}
+		return hancock.Allow(res.Obligations...)
+	})
+}
+
+// NewInput returns the Input of a validated request r made with info.
+func NewInput(r *http.Request, info *hancock.KeyInfo) *Input {
+	return &Input{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.Query(),
+		RemoteAddr: r.RemoteAddr,
+		Key:        info.Key,
+		Scopes:     info.Scopes,
+		Tier:       info.Tier,
+		Attributes: info.Attributes,
+		Claims:     hancock.ClaimsFromContext(r.Context()),
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Delivery is an outgoing webhook held by an Outbox until delivered.
+type Delivery struct {
+	// ID is the event id, which is kept across retries.
+	ID string
+	// Key is the API key the webhook is signed with.
+	Key    string
+	URL    string
+	Header http.Header
+	Body   []byte
+	// Attempt is the number of attempts made so far.
+	Attempt int
+	// Original is when the delivery was enqueued.
+	Original time.Time
+	// Next is when the next attempt is due.
+	Next time.Time
+}
+
+// copy returns a copy of d, for stores to hold what's outside callers' reach.
+func (d *Delivery) copy() *Delivery {
+	c := *d
+	c.Header = d.Header.Clone()
+	return &c
+}
+
+// OutboxStore persists the deliveries of an Outbox.
+type OutboxStore interface {
+	Add(d *Delivery) error
+	// Due returns up to limit deliveries due at now, leased until
+	// now+lease: they're held back from other calls to Due until then,
+	// unless updated or removed, so concurrent flushes don't deliver
+	// them twice.
+	Due(now time.Time, limit int, lease time.Duration) ([]*Delivery, error)
+	// Update saves a delivery rescheduled after a failed attempt.
+	Update(d *Delivery) error
+	// Remove drops a delivered, or abandoned, delivery.
+	Remove(id string) error
+}
+
+// OutboxStats counts an Outbox's delivery attempts.
+type OutboxStats struct {
+	Delivered uint64 `json:"delivered"`
+	Retried   uint64 `json:"retried"`
+	Abandoned uint64 `json:"abandoned"`
+}
+
+// Outbox delivers signed webhooks (see SignWebhook), retrying failures with
+// backoff. Every attempt is signed afresh, with the key's current secret.
+type Outbox struct {
+	// MaxAttempts is the number of attempts before a delivery is abandoned.
+	MaxAttempts int
+	// Backoff returns the delay before retrying after attempt failed.
+	Backoff func(attempt int) time.Duration
+	// BatchSize bounds the deliveries attempted per Flush.
+	BatchSize int
+	// Lease is how long a Flush holds the deliveries it attempts, after
+	// which they're due again, e.g. when the process died mid-delivery.
+	Lease time.Duration
+	// Clock times deliveries and their signatures, SystemClock when nil.
+	Clock Clock
+
+	store  OutboxStore
+	keys   KeyInfoFunc
+	client *http.Client
+
+	delivered atomic.Uint64
+	retried   atomic.Uint64
+	abandoned atomic.Uint64
+}
+
+// NewOutbox returns an Outbox persisting to store and sending with client,
+// making up to 8 attempts with exponential backoff from 1s.
+func NewOutbox(store OutboxStore, keys KeyInfoFunc, client *http.Client) *Outbox {
+	return &Outbox{
+		MaxAttempts: 8,
+		Backoff: func(attempt int) time.Duration {
+			return time.Second << uint(attempt-1)
+		},
+		BatchSize: 100,
+		Lease:     time.Minute,
+		store:     store,
+		keys:      keys,
+		client:    client,
+	}
+}
+
+// Enqueue stores d for delivery as soon as possible.
+func (o *Outbox) Enqueue(d *Delivery) error {
+	now := clockOr(o.Clock).Now()
+	if d.Original.IsZero() {
+		d.Original = now
+	}
+	if d.Next.IsZero() {
+		d.Next = now
+	}
+	return o.store.Add(d)
+}
+
+// Run flushes the outbox every interval until ctx is done.
+func (o *Outbox) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := o.Flush(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Flush attempts every due delivery once.
+func (o *Outbox) Flush(ctx context.Context) error {
+	due, err := o.store.Due(clockOr(o.Clock).Now(), o.BatchSize, o.Lease)
+	if err != nil {
+		return err
+	}
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return nil
+		}
+		d.Attempt++
+		if err := o.send(ctx, d); err == nil {
+			o.delivered.Add(1)
+			err = o.store.Remove(d.ID)
+		} else if d.Attempt >= o.MaxAttempts {
+			o.abandoned.Add(1)
+			err = o.store.Remove(d.ID)
+		} else {
+			o.retried.Add(1)
+			d.Next = clockOr(o.Clock).Now().Add(o.Backoff(d.Attempt))
+			err = o.store.Update(d)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats returns the outbox's delivery counts.
+func (o *Outbox) Stats() OutboxStats {
+	return OutboxStats{
+		Delivered: o.delivered.Load(),
+		Retried:   o.retried.Load(),
+		Abandoned: o.abandoned.Load(),
+	}
+}
+
+func (o *Outbox) send(ctx context.Context, d *Delivery) error {
+	info, err := o.keys(d.Key)
+	if err != nil {
+		return err
+	}
+	if info == nil || info.Secret == "" {
+		return fmt.Errorf("unknown webhook key `%s`", KeyAlias(d.Key))
+	}
+	r, err := http.NewRequestWithContext(ctx, "POST", d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return err
+	}
+	for k, v := range d.Header {
+		r.Header[k] = v
+	}
+	SignWebhook(r, d.Key, info.Secret, d.Body, WebhookMeta{
+		EventID:  d.ID,
+		Attempt:  d.Attempt,
+		Original: d.Original,
+	}, SignClock(clockOr(o.Clock)))
+	resp, err := o.client.Do(r)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook delivery failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// MemoryOutboxStore is an in-memory OutboxStore, losing its deliveries on
+// restart.
+type MemoryOutboxStore struct {
+	mu         sync.Mutex
+	deliveries map[string]*Delivery
+}
+
+// NewMemoryOutboxStore returns an empty MemoryOutboxStore.
+func NewMemoryOutboxStore() *MemoryOutboxStore {
+	return &MemoryOutboxStore{deliveries: make(map[string]*Delivery)}
+}
+
+// Add stores d.
+func (s *MemoryOutboxStore) Add(d *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.deliveries[d.ID]; ok {
+		return fmt.Errorf("duplicate delivery `%s`", d.ID)
+	}
+	s.deliveries[d.ID] = d.copy()
+	return nil
+}
+
+// Due returns copies of the earliest due deliveries, leased until
+// now+lease.
+func (s *MemoryOutboxStore) Due(now time.Time, limit int, lease time.Duration) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*Delivery
+	for _, d := range s.deliveries {
+		if !d.Next.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Next.Before(due[j].Next) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i, d := range due {
+		due[i] = d.copy()
+		d.Next = now.Add(lease)
+	}
+	return due, nil
+}
+
+// Update saves d.
+func (s *MemoryOutboxStore) Update(d *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[d.ID] = d.copy()
+	return nil
+}
+
+// Remove drops the delivery id.
+func (s *MemoryOutboxStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.deliveries, id)
+	return nil
+}
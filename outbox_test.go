@@ -0,0 +1,42 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryOutboxLease(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryOutboxStore()
+	s.Add(&Delivery{ID: "evt", Next: now})
+
+	due, _ := s.Due(now, 10, time.Minute)
+	if len(due) != 1 {
+		t.Fatalf("due %d deliveries, want 1", len(due))
+	}
+	// Callers own what's returned.
+	due[0].Attempt = 5
+
+	tests := []struct {
+		name string
+		at   time.Time
+		due  int
+	}{
+		{"leased", now, 0},
+		{"still leased", now.Add(59 * time.Second), 0},
+		{"lease expired", now.Add(time.Minute), 1},
+	}
+	for _, tt := range tests {
+		due, _ := s.Due(tt.at, 10, time.Minute)
+		if len(due) != tt.due {
+			t.Errorf("%s: due %d deliveries, want %d", tt.name, len(due), tt.due)
+		}
+		if len(due) > 0 && due[0].Attempt != 0 {
+			t.Errorf("%s: attempt %d, want 0", tt.name, due[0].Attempt)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pagePrefix starts the API key of page tokens.
+const pagePrefix = "page."
+
+// MintPageToken returns a short-lived API key and secret, derived from key's
+// private key, to embed in a rendered page so browser code can sign its
+// fetch calls without the long-lived secret ever reaching the client.
+//
+// Pages sign exactly as SignQS does, using the returned apikey and secret,
+// e.g. with WebCrypto:
+//
+//	const k = await crypto.subtle.importKey("raw", new TextEncoder().encode(secret),
+//		{name: "HMAC", hash: "SHA-256"}, false, ["sign"]);
+//	const mac = await crypto.subtle.sign("HMAC", k, new TextEncoder().encode(method + ":" + query));
+//
+// where query is the sorted, form encoded query (including apikey and ts),
+// and the signature is mac in padded URL-safe base64.
+//
+// Validators must be created with WithPageTokens to accept them. opts may
+// set the clock (see SignClock).
+func MintPageToken(key, pKey string, ttl time.Duration, opts ...SignOption) (apikey, secret string) {
+	apikey = pagePrefix + strconv.FormatInt(signNow(opts).Add(ttl).Unix(), 10) + "." + key
+	return apikey, pageSecret(pKey, apikey)
+}
+
+// WithPageTokens accepts requests signed with page tokens (see MintPageToken)
+// until they expire. The request's KeyInfo is that of the key the token was
+// minted from.
+func WithPageTokens() Option {
+	return func(v *Validator) {
+		v.pageTokens = true
+	}
+}
+
+func isPageKey(key string) bool {
+	return strings.HasPrefix(key, pagePrefix)
+}
+
+// pageKey returns the KeyInfo for a page token's API key.
+func (v *Validator) pageKey(apikey string) (*KeyInfo, error) {
+	exp, key, ok := strings.Cut(strings.TrimPrefix(apikey, pagePrefix), ".")
+	if !ok {
+		return nil, nil
+	}
+	t, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	if v.clock.Now().Unix() > t {
+		return nil, ErrExpiredToken
+	}
+
+	info, err := v.keyInfo(key)
+	if err != nil || info == nil {
+		return info, err
+	}
+	// Anyone can derive a page secret from an empty one, so keys holding
+	// only a public key have no page tokens.
+	if info.Secret == "" {
+		return nil, nil
+	}
+	derived := *info
+	derived.Secret = pageSecret(info.Secret, apikey)
+	derived.apikey = apikey
+	derived.Previous = nil
+	for _, p := range info.secrets()[1:] {
+		derived.Previous = append(derived.Previous, pageSecret(p, apikey))
+	}
+	return &derived, nil
+}
+
+func pageSecret(pKey, apikey string) string {
+	hash := hmac.New(sha256.New, []byte(pKey))
+	hash.Write([]byte("page:" + apikey))
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil))
+}
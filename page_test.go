@@ -0,0 +1,46 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/ed25519"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPageTokens(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewValidator(nil, WithPageTokens(), WithKeyInfo(keyMap(map[string]*KeyInfo{
+		"hmac":    {Key: "hmac", Secret: "secret", Previous: []string{"old"}, Expires: 300},
+		"ed25519": {Key: "ed25519", PublicKey: pub, Expires: 300},
+	})))
+	tests := []struct {
+		name   string
+		key    string
+		pKey   string
+		ttl    time.Duration
+		status int
+	}{
+		{"valid", "hmac", "secret", time.Minute, 0},
+		{"previous secret", "hmac", "old", time.Minute, 0},
+		{"wrong secret", "hmac", "other", time.Minute, http.StatusUnauthorized},
+		{"expired", "hmac", "secret", -time.Minute, http.StatusUnauthorized},
+		{"unknown key", "none", "", time.Minute, http.StatusUnauthorized},
+		// Anyone can derive a page secret from the empty secret.
+		{"public key only", "ed25519", "", time.Minute, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		apikey, secret := MintPageToken(tt.key, tt.pKey, tt.ttl)
+		r := httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, apikey, secret, "/", nil), nil)
+		if _, err := v.Validate(r); status(err) != tt.status {
+			t.Errorf("%s: status %d, want %d (%v)", tt.name, status(err), tt.status, err)
+		}
+	}
+}
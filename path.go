@@ -0,0 +1,78 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import "strings"
+
+// PathNormalization selects how signed paths (see Version4) are normalized
+// before signing, so clients and proxies normalizing paths differently don't
+// cause mismatches. Signers and validators must use the same normalization.
+type PathNormalization int
+
+const (
+	// CollapseSlashes collapses repeated slashes, `/a//b` is `/a/b`.
+	CollapseSlashes PathNormalization = 1 << iota
+	// DotSegments resolves `.` and `..` segments, `/a/./b/../c` is `/a/c`.
+	DotSegments
+	// TrailingSlash drops trailing slashes, `/a/` is `/a`.
+	TrailingSlash
+)
+
+// apply returns p normalized.
+func (n PathNormalization) apply(p string) string {
+	if n&CollapseSlashes != 0 {
+		for strings.Contains(p, "//") {
+			p = strings.ReplaceAll(p, "//", "/")
+		}
+	}
+	if n&DotSegments != 0 {
+		p = removeDotSegments(p)
+	}
+	if n&TrailingSlash != 0 && len(p) > 1 {
+		if p = strings.TrimRight(p, "/"); p == "" {
+			p = "/"
+		}
+	}
+	return p
+}
+
+// removeDotSegments resolves the `.` and `..` segments of p, never above
+// its root.
+func removeDotSegments(p string) string {
+	segs := strings.Split(p, "/")
+	out := make([]string, 0, len(segs))
+	for i, s := range segs {
+		switch s {
+		case ".":
+		case "..":
+			if len(out) > 1 || (len(out) == 1 && out[0] != "") {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, s)
+			continue
+		}
+		if i == len(segs)-1 {
+			out = append(out, "") // keep `/a/.` a directory
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+// WithPathNormalization normalizes the path of requests before checking
+// signatures that sign it (see SignPathNormalization).
+func WithPathNormalization(n PathNormalization) Option {
+	return func(v *Validator) {
+		v.paths = n
+	}
+}
+
+// SignPathNormalization normalizes the signed path, matching validators
+// using WithPathNormalization.
+func SignPathNormalization(n PathNormalization) SignOption {
+	return func(c *signConfig) {
+		c.paths = n
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ValidationPool bounds the validations running at once, e.g. in front of
+// a proxy, so a flood of expensive validations (body hashing, remote key
+// lookups) sheds load rather than piling up.
+type ValidationPool struct {
+	slots chan struct{}
+	queue int64
+
+	waiting atomic.Int64
+	shed    atomic.Uint64
+}
+
+// PoolStats reports a ValidationPool's load.
+type PoolStats struct {
+	Active  int    `json:"active"`
+	Waiting int64  `json:"waiting"`
+	Shed    uint64 `json:"shed"`
+}
+
+// NewValidationPool returns a pool running up to workers validations at
+// once, with up to queue more waiting for a worker.
+func NewValidationPool(workers, queue int) *ValidationPool {
+	return &ValidationPool{slots: make(chan struct{}, workers), queue: int64(queue)}
+}
+
+// WithValidationPool runs Handler's validations in p. Requests beyond its
+// queue are shed with a 503 and Retry-After. Its load is reported in
+// Status.
+func WithValidationPool(p *ValidationPool) Option {
+	return func(v *Validator) {
+		v.pool = p
+	}
+}
+
+// Stats returns p's current load.
+func (p *ValidationPool) Stats() PoolStats {
+	return PoolStats{Active: len(p.slots), Waiting: p.waiting.Load(), Shed: p.shed.Load()}
+}
+
+// acquire takes a worker, waiting in the queue when there's room, reporting
+// false when the request is shed or ctx is done first.
+func (p *ValidationPool) acquire(ctx context.Context) bool {
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if p.waiting.Add(1) > p.queue {
+		p.waiting.Add(-1)
+		p.shed.Add(1)
+		return false
+	}
+	defer p.waiting.Add(-1)
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (p *ValidationPool) release() {
+	<-p.slots
+}
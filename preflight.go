@@ -0,0 +1,97 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DriftFunc returns the offset of the local clock from a reference clock,
+// e.g. measured against an NTP server.
+type DriftFunc func() (time.Duration, error)
+
+// WithDriftCheck has Check fail when the clock is off by more than max
+// according to fn. A drifting clock rejects every request as expired.
+func WithDriftCheck(fn DriftFunc, max time.Duration) Option {
+	return func(v *Validator) {
+		v.drift = fn
+		v.maxDrift = max
+	}
+}
+
+// WithWarmKeys names keys Start looks up, warming caches (e.g. Quarantine)
+// and proving the key store holds the expected keys.
+func WithWarmKeys(keys ...string) Option {
+	return func(v *Validator) {
+		v.warm = append(v.warm, keys...)
+	}
+}
+
+// Check verifies the Validator's configuration is consistent, its checks
+// (see WithCheck) pass, and its clock hasn't drifted (see WithDriftCheck).
+// It returns every failure joined, or nil.
+func (v *Validator) Check() error {
+	var errs []error
+	seen := make(map[string]bool)
+	for _, n := range v.params.names() {
+		if n == "" {
+			errs = append(errs, errors.New("hancock: empty parameter name"))
+		} else if seen[n] {
+			errs = append(errs, fmt.Errorf("hancock: parameter `%s` used twice", n))
+		}
+		seen[n] = true
+	}
+	if v.keys == nil {
+		errs = append(errs, errors.New("hancock: no key lookup"))
+	}
+	switch v.expireSeconds() {
+	case -2:
+		errs = append(errs, errors.New("hancock: validation disabled (expires -2)"))
+	case 0:
+		if v.policy != nil {
+			errs = append(errs, errors.New("hancock: expire policy window under a second"))
+		}
+	}
+	if len(v.acceptedAlgorithms()) == 0 {
+		errs = append(errs, errors.New("hancock: no accepted algorithm"))
+	}
+	if len(v.acceptedVersions()) == 0 {
+		errs = append(errs, errors.New("hancock: no accepted version"))
+	}
+	for _, c := range v.checks {
+		if err := c.c.Check(); err != nil {
+			errs = append(errs, fmt.Errorf("hancock: check %s: %w", c.name, err))
+		}
+	}
+	if v.drift != nil {
+		d, err := v.drift()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("hancock: drift check: %w", err))
+		} else if d > v.maxDrift || d < -v.maxDrift {
+			errs = append(errs, fmt.Errorf("hancock: clock drift %s exceeds %s", d, v.maxDrift))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Start checks the Validator (see Check), then looks up its warm keys (see
+// WithWarmKeys), so misconfigurations fail at boot rather than rejecting
+// all traffic.
+func (v *Validator) Start() error {
+	errs := []error{v.Check()}
+	if v.keys != nil {
+		for _, key := range v.warm {
+			info, err := v.keyInfo(key)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("hancock: key lookup: %w", err))
+			} else if info == nil || (info.Secret == "" && info.PublicKey == nil) {
+				errs = append(errs, fmt.Errorf("hancock: unknown warm key `%s`", KeyAlias(key)))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,49 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Hint is a protected resource to preload.
+type Hint struct {
+	URL string
+	// As is the preload destination, e.g. "script", "style" or "image".
+	As string
+}
+
+// Preload adds Link preload headers to w for hints, their URLs signed with
+// the KeyInfo of the validated request r (see FromContext), so browsers can
+// prefetch them. Requests made with a page or impersonation token have
+// the hints signed with it. With early set, the links are also sent as a 103 Early
+// Hints response, and must be added before the final response is written.
+//
+// It reports whether r carried a KeyInfo to sign with.
+func Preload(w http.ResponseWriter, r *http.Request, hints []Hint, early bool, opts ...SignOption) bool {
+	info, ok := FromContext(r.Context())
+	if !ok || info.Secret == "" {
+		return false
+	}
+	for _, h := range hints {
+		urlStr, qs := h.URL, url.Values(nil)
+		if u, err := url.Parse(h.URL); err == nil && u.RawQuery != "" {
+			qs = u.Query()
+			u.RawQuery = ""
+			urlStr = u.String()
+		}
+		link := fmt.Sprintf("<%s>; rel=preload", Sign("GET", info.signingKey(), info.Secret, urlStr, qs, opts...))
+		if h.As != "" {
+			link += "; as=" + h.As
+		}
+		w.Header().Add("Link", link)
+	}
+	if early && len(hints) > 0 {
+		w.WriteHeader(http.StatusEarlyHints)
+	}
+	return true
+}
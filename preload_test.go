@@ -0,0 +1,50 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreload(t *testing.T) {
+	master := []byte("master")
+	v := NewValidator(nil, WithPageTokens(), WithImpersonation(master, nil), WithKeyInfo(keyMap(map[string]*KeyInfo{
+		"key": {Key: "key", Secret: "secret", Expires: 300},
+	})))
+	var link string
+	h := v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Preload(w, r, []Hint{{URL: "/app.js", As: "script"}}, false)
+		link = w.Header().Get("Link")
+	}))
+
+	page := func() (string, string) { return MintPageToken("key", "secret", time.Minute) }
+	tests := []struct {
+		name string
+		cred func() (string, string)
+	}{
+		{"key", func() (string, string) { return "key", "secret" }},
+		// Derived keys sign hints with their own API key.
+		{"page token", page},
+		{"impersonation", func() (string, string) { return MintImpersonation(master, "key", "staff", "ticket 1", time.Minute) }},
+	}
+	for _, tt := range tests {
+		link = ""
+		apikey, secret := tt.cred()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, apikey, secret, "/", nil), nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d", tt.name, w.Code)
+			continue
+		}
+		hint, _, _ := strings.Cut(strings.TrimPrefix(link, "<"), ">")
+		if _, err := v.Validate(httptest.NewRequest(http.MethodGet, hint, nil)); err != nil {
+			t.Errorf("%s: hint %q: %v", tt.name, hint, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"sync"
+	"time"
+)
+
+// Quarantine remembers unknown keys for a while, so scanners brute-forcing
+// API keys don't reach the key store with repeated lookups of the same key.
+type Quarantine struct {
+	fn    KeyInfoFunc
+	ttl   time.Duration
+	max   int
+	clock Clock
+
+	mu      sync.Mutex
+	unknown map[string]time.Time
+	hits    uint64
+	misses  uint64
+}
+
+// NewQuarantine returns a Quarantine in front of fn, remembering up to max
+// unknown keys for ttl each.
+func NewQuarantine(fn KeyInfoFunc, ttl time.Duration, max int) *Quarantine {
+	return &Quarantine{fn: fn, ttl: ttl, max: max, clock: SystemClock{}, unknown: make(map[string]time.Time)}
+}
+
+// WithClock sets the clock quarantined keys expire by.
+func (q *Quarantine) WithClock(c Clock) *Quarantine {
+	q.clock = c
+	return q
+}
+
+// KeyInfo is a KeyInfoFunc that short-circuits lookups of quarantined keys.
+func (q *Quarantine) KeyInfo(key string) (*KeyInfo, error) {
+	now := q.clock.Now()
+	q.mu.Lock()
+	if exp, ok := q.unknown[key]; ok {
+		if now.Before(exp) {
+			q.hits++
+			q.mu.Unlock()
+			return nil, nil
+		}
+		delete(q.unknown, key)
+	}
+	q.misses++
+	q.mu.Unlock()
+
+	info, err := q.fn(key)
+	if err == nil && info == nil {
+		q.add(key, now)
+	}
+	return info, err
+}
+
+// Stats returns the number of lookups answered from, and passed through, the quarantine.
+func (q *Quarantine) Stats() (hits, misses uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.hits, q.misses
+}
+
+func (q *Quarantine) add(key string, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.unknown) >= q.max {
+		for k, exp := range q.unknown {
+			if !now.Before(exp) {
+				delete(q.unknown, k)
+			}
+		}
+	}
+	// Still full, make room by evicting an arbitrary key.
+	for k := range q.unknown {
+		if len(q.unknown) < q.max {
+			break
+		}
+		delete(q.unknown, k)
+	}
+	if q.max > 0 {
+		q.unknown[key] = now.Add(q.ttl)
+	}
+}
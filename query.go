@@ -0,0 +1,120 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WithStrictQuery rejects query strings that aren't valid RFC 3986 queries
+// before validating them: malformed percent-encoding, control characters,
+// other characters needing escaping, and ';' separated parameters. net/url
+// decodes some of those leniently, so the parameters validated may differ
+// from those the client signed.
+func WithStrictQuery() Option {
+	return func(v *Validator) {
+		v.strict = true
+	}
+}
+
+// WithStrictParams rejects requests whose signing parameters are ambiguous:
+// repeated, sent both in the query and by the carrier (see WithCarrier), or
+// shadowed by parameters differing only in case. Downstream parsers may
+// pick another of them than hancock validated. Requests signed with several
+// signatures (see SignAlsoWith and SignAlgorithms) are rejected too.
+func WithStrictParams() Option {
+	return func(v *Validator) {
+		v.strictParams = true
+	}
+}
+
+// queryError returns why raw isn't a strict query, or nil.
+func queryError(raw string) error {
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '%':
+			if i+2 >= len(raw) || !isHex(raw[i+1]) || !isHex(raw[i+2]) {
+				return fmt.Errorf("malformed escape at %d", i)
+			}
+			i += 2
+		case c == ';':
+			return fmt.Errorf("';' at %d", i)
+		case c < 0x20 || c == 0x7f:
+			return fmt.Errorf("control character at %d", i)
+		case !isQueryChar(c):
+			return fmt.Errorf("invalid character %q at %d", c, i)
+		}
+	}
+	return nil
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// isQueryChar reports whether c may appear unescaped in a query: an
+// unreserved or sub-delim character, ':', '@', '/' or '?'.
+func isQueryChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	switch c {
+	case '-', '.', '_', '~', '!', '$', '&', '\'', '(', ')', '*', '+', ',', '=', ':', '@', '/', '?':
+		return true
+	}
+	return false
+}
+
+// checkQuery checks r's query, when v is strict.
+func (v *Validator) checkQuery(r *http.Request) *Error {
+	if !v.strict {
+		return nil
+	}
+	if err := queryError(r.URL.RawQuery); err != nil {
+		return v.newError(http.StatusBadRequest, r, "malformed query: %s", err)
+	}
+	return nil
+}
+
+// checkParams checks the signing parameters of q, when v is strict.
+func (v *Validator) checkParams(r *http.Request, q url.Values) *Error {
+	if !v.strictParams {
+		return nil
+	}
+	names := v.params.names()
+	for _, n := range names {
+		if len(q[n]) > 1 {
+			return v.newError(http.StatusBadRequest, r, "repeated parameter %q", n)
+		}
+	}
+	for k := range q {
+		for _, n := range names {
+			if k != n && strings.EqualFold(k, n) {
+				return v.newError(http.StatusBadRequest, r, "parameter %q shadows %q", k, n)
+			}
+		}
+	}
+	return nil
+}
+
+// checkCarried checks that none of the signing parameters carried outside
+// r's query are also in it, when v is strict.
+func (v *Validator) checkCarried(r *http.Request, signing url.Values) *Error {
+	if !v.strictParams {
+		return nil
+	}
+	q := r.URL.Query()
+	for n := range signing {
+		if _, ok := q[n]; ok {
+			return v.newError(http.StatusBadRequest, r, "parameter %q carried twice", n)
+		}
+	}
+	return nil
+}
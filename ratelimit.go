@@ -0,0 +1,152 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter budgets requests per API key.
+type RateLimiter interface {
+	// Allow consumes cost from key's budget, reporting false when there
+	// isn't enough left.
+	Allow(key string, cost int) bool
+}
+
+// TokenBucket is an in-memory RateLimiter giving each key a bucket of burst
+// tokens, refilled at rate tokens per second.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+	clock Clock
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a TokenBucket refilling rate tokens per second, up to burst.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{rate: rate, burst: float64(burst), clock: SystemClock{}, buckets: make(map[string]*bucket)}
+}
+
+// WithClock sets the clock buckets are refilled by.
+func (l *TokenBucket) WithClock(c Clock) *TokenBucket {
+	l.clock = c
+	return l
+}
+
+// Allow consumes cost tokens from key's bucket.
+func (l *TokenBucket) Allow(key string, cost int) bool {
+	now := l.clock.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	if b.tokens < float64(cost) {
+		return false
+	}
+	b.tokens -= float64(cost)
+	return true
+}
+
+// Remaining returns the whole tokens left in key's bucket, and its size.
+func (l *TokenBucket) Remaining(key string) (left, size int) {
+	now := l.clock.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		return int(l.burst), int(l.burst)
+	}
+	tokens := b.tokens + now.Sub(b.last).Seconds()*l.rate
+	if tokens > l.burst {
+		tokens = l.burst
+	}
+	return int(tokens), int(l.burst)
+}
+
+// Budget reports what's left of rate limit budgets.
+type Budget interface {
+	// Remaining returns what's left of key's budget, and its size.
+	Remaining(key string) (left, size int)
+}
+
+// RateLimitWarningHeader is set on responses to keys past a SoftLimit.
+const RateLimitWarningHeader = "X-Hancock-RateLimit-Warning"
+
+// SoftLimit is a RateLimiter warning keys nearing the end of their budget,
+// before they're limited. Responses past Threshold carry the
+// RateLimitWarningHeader, and the RateLimit-Limit and RateLimit-Remaining
+// headers.
+type SoftLimit struct {
+	// Limiter limits requests, and must implement Budget.
+	Limiter RateLimiter
+	// Threshold is the fraction of the budget used, e.g. 0.8, past which
+	// requests are warned.
+	Threshold float64
+	// OnWarn, when set, is called for each warned request.
+	OnWarn func(key string, left, size int)
+}
+
+// Allow consumes cost from key's budget.
+func (s *SoftLimit) Allow(key string, cost int) bool {
+	return s.Limiter.Allow(key, cost)
+}
+
+// warn sets the warning headers on w when key is past the threshold.
+func (s *SoftLimit) warn(w http.ResponseWriter, key string) {
+	b, ok := s.Limiter.(Budget)
+	if !ok {
+		return
+	}
+	left, size := b.Remaining(key)
+	if size <= 0 || float64(size-left) < s.Threshold*float64(size) {
+		return
+	}
+	w.Header().Set(RateLimitWarningHeader, "approaching rate limit")
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(size))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(left))
+	if s.OnWarn != nil {
+		s.OnWarn(key, left, size)
+	}
+}
+
+// RateLimit returns middleware that consumes cost from the budget of the key
+// that signed the request, responding with 429 once it's exhausted.
+// It must be wrapped by a Validator's Handler; unvalidated (exempt)
+// requests aren't limited. A SoftLimit also warns keys nearing their limit.
+func RateLimit(l RateLimiter, cost int) func(http.Handler) http.Handler {
+	soft, _ := l.(*SoftLimit)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info, ok := FromContext(r.Context())
+			if ok && !l.Allow(info.Key, cost) {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			if ok && soft != nil {
+				soft.warn(w, info.Key)
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
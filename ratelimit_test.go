@@ -0,0 +1,78 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketClock(t *testing.T) {
+	clock := newTestClock()
+	l := NewTokenBucket(1, 2).WithClock(clock)
+	for i, want := range []bool{true, true, false} {
+		if got := l.Allow("key", 1); got != want {
+			t.Errorf("allow %d: %v, want %v", i, got, want)
+		}
+	}
+	clock.now = clock.now.Add(time.Second)
+	if !l.Allow("key", 1) {
+		t.Error("not refilled after a second")
+	}
+	if left, _ := l.Remaining("key"); left != 0 {
+		t.Errorf("remaining %d, want 0", left)
+	}
+}
+
+// keyRecorder is a RateLimiter and Semaphore recording the keys limited.
+type keyRecorder struct {
+	keys []string
+}
+
+func (l *keyRecorder) Allow(key string, cost int) bool {
+	l.keys = append(l.keys, key)
+	return true
+}
+
+func (l *keyRecorder) Acquire(key string) bool {
+	l.keys = append(l.keys, key)
+	return true
+}
+
+func (l *keyRecorder) Release(key string) {}
+
+func TestLimitedKeys(t *testing.T) {
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		name string
+		v    *Validator
+	}{
+		{"KeyFunc", NewValidator(func(key string) (string, int) { return "secret", 300 })},
+		// KeyInfos leaving out their Key.
+		{"KeyInfoFunc", NewValidator(nil, WithKeyInfo(keyMap(map[string]*KeyInfo{
+			"a": {Secret: "secret", Expires: 300},
+			"b": {Secret: "secret", Expires: 300},
+		})))},
+	}
+	for _, tt := range tests {
+		rate, sem := new(keyRecorder), new(keyRecorder)
+		h := tt.v.Handler(RateLimit(rate, 1)(ConcurrencyLimit(sem)(noop)))
+		for _, key := range []string{"a", "b"} {
+			r := httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, key, "secret", "/", nil), nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: status %d", tt.name, w.Code)
+			}
+		}
+		for _, l := range []*keyRecorder{rate, sem} {
+			if len(l.keys) != 2 || l.keys[0] != "a" || l.keys[1] != "b" {
+				t.Errorf("%s: limited keys %q, want [a b]", tt.name, l.keys)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+)
+
+// Redacted replaces the signatures redacted in logs.
+const Redacted = "REDACTED"
+
+// redactor redacts the signature and API key of signed URLs.
+type redactor struct {
+	params Params
+	re     *regexp.Regexp
+}
+
+func newRedactor(p Params) *redactor {
+	// Parameters in URLs, escaped URLs (e.g. redirect targets) and JSON.
+	sep := `(?:^|[?&;]|%26|%3[fF]|\\u0026)`
+	names := regexp.QuoteMeta(p.Signature) + "|" + regexp.QuoteMeta(p.APIKey)
+	return &redactor{
+		params: p,
+		re:     regexp.MustCompile(`(` + sep + `)(` + names + `)(=|%3[dD])([^&\s"'#;,%<>]*)`),
+	}
+}
+
+var defaultRedactor = newRedactor(DefaultParams)
+
+func (rd *redactor) redact(s string) string {
+	return rd.re.ReplaceAllStringFunc(s, func(m string) string {
+		sub := rd.re.FindStringSubmatch(m)
+		val := Redacted
+		if sub[2] == rd.params.APIKey {
+			val = KeyAlias(sub[4])
+		}
+		return sub[1] + sub[2] + sub[3] + val
+	})
+}
+
+// Redact returns s, e.g. a log line, with the signatures of the signed URLs
+// in it redacted and their API keys replaced by their KeyAlias, so leaked
+// logs don't hold credentials replayable until the URLs expire.
+func Redact(s string) string {
+	return defaultRedactor.redact(s)
+}
+
+// Redact is Redact for the signing parameters of v.
+func (v *Validator) Redact(s string) string {
+	if v.params == DefaultParams {
+		return Redact(s)
+	}
+	return newRedactor(v.params).redact(s)
+}
+
+// RedactWriter returns a writer redacting what's written to w (see Redact),
+// e.g. for log.SetOutput. Each write is redacted on its own, as log.Logger
+// writes whole lines.
+func RedactWriter(w io.Writer) io.Writer {
+	return &redactWriter{w}
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+func (rw *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// RedactRequest returns a shallow copy of r with its URL, RequestURI and
+// Referer redacted (see Redact), for access logs. r itself is left as is,
+// to be validated.
+func RedactRequest(r *http.Request) *http.Request {
+	r2 := *r
+	u := *r.URL
+	u.RawQuery = Redact(u.RawQuery)
+	r2.URL = &u
+	r2.RequestURI = Redact(r.RequestURI)
+	if ref := r.Header.Get("Referer"); ref != "" {
+		r2.Header = r.Header.Clone()
+		r2.Header.Set("Referer", Redact(ref))
+	}
+	return &r2
+}
@@ -0,0 +1,72 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redirect replies to a validated request r with a redirect to urlStr,
+// e.g. to a canonical host or trailing slash form, re-signed with the key
+// r was signed with, its version, algorithm, encoding and claims, so
+// clients following it aren't rejected. The destination's query is signed,
+// or r's when it has none. code should be http.StatusMovedPermanently or
+// http.StatusPermanentRedirect.
+//
+// Requests validated with a public key (see SignQSKey) can't be re-signed,
+// and are redirected as is.
+func (v *Validator) Redirect(w http.ResponseWriter, r *http.Request, urlStr string, code int) {
+	info, ok := FromContext(r.Context())
+	u, err := url.Parse(urlStr)
+	if !ok || info.Secret == "" || err != nil {
+		http.Redirect(w, r, urlStr, code)
+		return
+	}
+
+	p := v.params
+	q := r.URL.Query()
+	// Derived keys, e.g. page tokens, are signed with their own API key.
+	key := q.Get(p.APIKey)
+	if key == "" {
+		key = info.Key
+	}
+	opts := []SignOption{
+		SignParams(p),
+		SignVersion(q.Get(p.Version)),
+		SignAlgorithm(q.Get(p.Algorithm)),
+		SignEncoding(q.Get(p.Encoding)),
+	}
+	if v.bindHost {
+		host := strings.ToLower(u.Host)
+		if host == "" {
+			if host = v.host; host == "" {
+				host = requestHost(r)
+			}
+		}
+		opts = append(opts, SignHost(host))
+	}
+	if v.epoch > 0 {
+		opts = append(opts, SignEpoch(v.epoch))
+	}
+	if v.dates {
+		opts = append(opts, SignDateScope(v.service))
+	}
+	if v.replay != nil {
+		opts = append(opts, SignNonce())
+	}
+	if claims := ClaimsFromContext(r.Context()); claims != nil {
+		opts = append(opts, SignClaims(claims))
+	}
+
+	values := u.Query()
+	if u.RawQuery == "" {
+		values = q
+	}
+	p.strip(values)
+	u.RawQuery = ""
+	http.Redirect(w, r, Sign(r.Method, key, info.Secret, u.String(), values, opts...), code)
+}
@@ -0,0 +1,86 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package redis provides a hancock.ReplayStore backed by Redis, so the
+// instances of a deployment share nonces (see hancock.WithReplayStore).
+//
+//	pool := redis.NewPool("localhost:6379", 16)
+//	v := hancock.NewValidator(keyFn, hancock.WithReplayStore(&redis.ReplayStore{
+//		Pool: pool,
+//	}))
+package redis
+
+import (
+	"errors"
+	"time"
+
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+// DefaultPrefix prefixes the keys of nonces unless configured otherwise.
+const DefaultPrefix = "hancock:nonce:"
+
+// ReplayStore records nonces in Redis, each expiring once its request
+// does.
+type ReplayStore struct {
+	Pool *redigo.Pool
+	// Prefix prefixes the keys of nonces, DefaultPrefix when empty.
+	Prefix string
+	// FailClosed treats nonces as replayed when Redis can't be reached,
+	// rejecting every request. By default they're treated as new, allowing
+	// replays until Redis recovers.
+	FailClosed bool
+	// OnError, when set, is called with errors reaching Redis.
+	OnError func(error)
+}
+
+// NewPool returns a pool of up to maxIdle idle connections to the Redis
+// server at addr, checking connections idle for over a minute before use.
+func NewPool(addr string, maxIdle int) *redigo.Pool {
+	return &redigo.Pool{
+		Dial: func() (redigo.Conn, error) {
+			return redigo.Dial("tcp", addr)
+		},
+		TestOnBorrow: func(c redigo.Conn, t time.Time) error {
+			if time.Since(t) < time.Minute {
+				return nil
+			}
+			_, err := c.Do("PING")
+			return err
+		},
+		MaxIdle:     maxIdle,
+		IdleTimeout: 5 * time.Minute,
+	}
+}
+
+// Seen records nonce, reporting whether it was already recorded.
+func (s *ReplayStore) Seen(nonce string, now, exp time.Time) bool {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	args := []interface{}{prefix + nonce, 1, "NX"}
+	// Nonces without an expiry are kept forever.
+	if !exp.IsZero() {
+		ttl := exp.Sub(now).Milliseconds()
+		if ttl < 1 {
+			ttl = 1
+		}
+		args = append(args, "PX", ttl)
+	}
+
+	conn := s.Pool.Get()
+	defer conn.Close()
+	_, err := redigo.String(conn.Do("SET", args...))
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, redigo.ErrNil):
+		return true
+	}
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+	return s.FailClosed
+}
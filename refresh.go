@@ -0,0 +1,192 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrRefreshReused is returned when a refresh credential that was already
+// renewed is presented again. The key's refresh credential is revoked, as
+// either the client or an attacker holds a leaked copy.
+var ErrRefreshReused = errors.New("hancock: refresh credential reused")
+
+// RefreshStore tracks the current refresh credential of each API key.
+type RefreshStore interface {
+	// Current returns the id of key's current refresh credential, or "".
+	Current(key string) (string, error)
+	// Swap replaces key's refresh credential old with new, reporting false
+	// when old is no longer current.
+	Swap(key, old, new string) (bool, error)
+}
+
+// Refresher issues long-lived refresh credentials, which can only be
+// renewed for short-lived signing credentials (page tokens, see
+// MintPageToken) and a replacement refresh credential. Devices storing only
+// the refresh credential limit what a leak exposes.
+type Refresher struct {
+	keys  KeyInfoFunc
+	store RefreshStore
+	ttl   time.Duration
+	clock Clock
+}
+
+type refreshToken struct {
+	Key string `json:"key"`
+	ID  string `json:"id"`
+}
+
+// Credentials are the result of a renewal.
+type Credentials struct {
+	Refresh string `json:"refresh"`
+	APIKey  string `json:"apikey"`
+	Secret  string `json:"secret"`
+	Expires int64  `json:"expires"`
+}
+
+// NewRefresher returns a Refresher issuing signing credentials valid for ttl.
+func NewRefresher(keys KeyInfoFunc, store RefreshStore, ttl time.Duration) *Refresher {
+	return &Refresher{keys: keys, store: store, ttl: ttl, clock: SystemClock{}}
+}
+
+// WithClock sets the clock signing credentials expire by.
+func (f *Refresher) WithClock(c Clock) *Refresher {
+	f.clock = c
+	return f
+}
+
+// Issue returns a new refresh credential for key, revoking any previous one.
+func (f *Refresher) Issue(key string) (string, error) {
+	info, err := f.lookup(key)
+	if err != nil {
+		return "", err
+	}
+	old, err := f.store.Current(info.Key)
+	if err != nil {
+		return "", err
+	}
+	return f.swap(info, old)
+}
+
+// Renew exchanges a refresh credential for short-lived signing credentials
+// and the next refresh credential.
+func (f *Refresher) Renew(refresh string) (*Credentials, error) {
+	t := new(refreshToken)
+	if err := decodeToken(refresh, t); err != nil {
+		return nil, err
+	}
+	info, err := f.lookup(t.Key)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyToken("refresh", info.Secret, refresh); err != nil {
+		return nil, err
+	}
+
+	next, err := f.swap(info, t.ID)
+	if errors.Is(err, ErrRefreshReused) {
+		// Revoke the whole chain.
+		if cur, _ := f.store.Current(info.Key); cur != "" {
+			f.store.Swap(info.Key, cur, "")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	apikey, secret := MintPageToken(info.Key, info.Secret, f.ttl, SignClock(f.clock))
+	return &Credentials{
+		Refresh: next,
+		APIKey:  apikey,
+		Secret:  secret,
+		Expires: f.clock.Now().Add(f.ttl).Unix(),
+	}, nil
+}
+
+// Handler returns the renewal endpoint, exchanging the "refresh" form value
+// of POST requests for Credentials written as JSON.
+func (f *Refresher) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		c, err := f.Renew(r.PostFormValue("refresh"))
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(c)
+	})
+}
+
+func (f *Refresher) lookup(key string) (*KeyInfo, error) {
+	info, err := f.keys(key)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil || info.Secret == "" {
+		return nil, ErrInvalidToken
+	}
+	return keyed(info, key), nil
+}
+
+// swap replaces the key's refresh credential old with a new one.
+func (f *Refresher) swap(info *KeyInfo, old string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	ok, err := f.store.Swap(info.Key, old, id)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrRefreshReused
+	}
+	return signToken("refresh", info.Secret, &refreshToken{Key: info.Key, ID: id}), nil
+}
+
+// MemoryRefreshStore is an in-memory RefreshStore.
+type MemoryRefreshStore struct {
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+// NewMemoryRefreshStore returns an empty MemoryRefreshStore.
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{ids: make(map[string]string)}
+}
+
+// Current returns key's current refresh credential id.
+func (s *MemoryRefreshStore) Current(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ids[key], nil
+}
+
+// Swap replaces key's refresh credential id old with new.
+func (s *MemoryRefreshStore) Swap(key, old, new string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ids[key] != old {
+		return false, nil
+	}
+	if new == "" {
+		delete(s.ids, key)
+	} else {
+		s.ids[key] = new
+	}
+	return true, nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRefresher(t *testing.T) {
+	tests := []struct {
+		name  string
+		infos map[string]*KeyInfo
+	}{
+		{"keyed", map[string]*KeyInfo{"a": {Key: "a", Secret: "secret a"}, "b": {Key: "b", Secret: "secret b"}}},
+		// KeyInfoFuncs may leave Key out.
+		{"unkeyed", map[string]*KeyInfo{"a": {Secret: "secret a"}, "b": {Secret: "secret b"}}},
+	}
+	for _, tt := range tests {
+		f := NewRefresher(keyMap(tt.infos), NewMemoryRefreshStore(), time.Minute)
+		a, err := f.Issue("a")
+		if err != nil {
+			t.Fatalf("%s: issue a: %v", tt.name, err)
+		}
+		// Keys have refresh credentials of their own.
+		if _, err := f.Issue("b"); err != nil {
+			t.Fatalf("%s: issue b: %v", tt.name, err)
+		}
+		c, err := f.Renew(a)
+		if err != nil {
+			t.Fatalf("%s: renew: %v", tt.name, err)
+		}
+		if !isPageKey(c.APIKey) || c.Refresh == "" {
+			t.Errorf("%s: credentials %+v", tt.name, c)
+		}
+		if _, err := f.Renew(a); !errors.Is(err, ErrRefreshReused) {
+			t.Errorf("%s: reuse: %v, want ErrRefreshReused", tt.name, err)
+		}
+		// Reuse revokes the chain.
+		if _, err := f.Renew(c.Refresh); !errors.Is(err, ErrRefreshReused) {
+			t.Errorf("%s: renew after reuse: %v, want ErrRefreshReused", tt.name, err)
+		}
+	}
+}
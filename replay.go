@@ -0,0 +1,98 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrReplayed is returned for single-use requests seen before.
+var ErrReplayed = errors.New("hancock: replayed")
+
+// ReplayStore records single-use nonces.
+type ReplayStore interface {
+	// Seen records nonce at now, the validator's time (see WithClock),
+	// reporting whether it was already recorded. Nonces need only be kept
+	// until exp, when the request carrying them stops being accepted, or
+	// forever when exp is zero (e.g. with timestamps unchecked).
+	Seen(nonce string, now, exp time.Time) bool
+}
+
+// forever is the expiry of nonces kept forever.
+var forever = time.Unix(1<<62, 0)
+
+// MemoryReplayStore is an in-memory ReplayStore, for single instances.
+type MemoryReplayStore struct {
+	// Evict, once the store is full, evicts the nonces closest to expiring
+	// to record new ones, which may then be replayed until they would have
+	// expired. By default new nonces are treated as replayed, rejecting
+	// their requests, until recorded ones expire: otherwise a single client
+	// could flood the store to replay others' requests.
+	Evict bool
+
+	mu    sync.Mutex
+	max   int
+	seen  map[string]time.Time
+	byExp nonceHeap
+}
+
+// NewMemoryReplayStore returns a MemoryReplayStore keeping up to max nonces
+// (any number when max is 0) until their requests expire.
+func NewMemoryReplayStore(max int) *MemoryReplayStore {
+	return &MemoryReplayStore{max: max, seen: make(map[string]time.Time)}
+}
+
+// Seen records nonce, reporting whether it was already recorded.
+func (s *MemoryReplayStore) Seen(nonce string, now, exp time.Time) bool {
+	if exp.IsZero() {
+		exp = forever
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.byExp) > 0 && now.After(s.byExp[0].exp) {
+		delete(s.seen, heap.Pop(&s.byExp).(nonceExp).nonce)
+	}
+	if exp, ok := s.seen[nonce]; ok && !now.After(exp) {
+		return true
+	}
+	if s.max > 0 && len(s.byExp) >= s.max && !s.Evict {
+		return true
+	}
+	for s.max > 0 && len(s.byExp) >= s.max {
+		delete(s.seen, heap.Pop(&s.byExp).(nonceExp).nonce)
+	}
+	s.seen[nonce] = exp
+	heap.Push(&s.byExp, nonceExp{nonce, exp})
+	return false
+}
+
+// Len returns the number of nonces recorded.
+func (s *MemoryReplayStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.seen)
+}
+
+type nonceExp struct {
+	nonce string
+	exp   time.Time
+}
+
+// nonceHeap orders nonces by expiry, soonest first.
+type nonceHeap []nonceExp
+
+func (h nonceHeap) Len() int            { return len(h) }
+func (h nonceHeap) Less(i, j int) bool  { return h[i].exp.Before(h[j].exp) }
+func (h nonceHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *nonceHeap) Push(x interface{}) { *h = append(*h, x.(nonceExp)) }
+func (h *nonceHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,65 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"context"
+	"net/http"
+)
+
+// Scopes returns the scopes of the key that signed the request.
+func Scopes(ctx context.Context) []string {
+	if info, ok := FromContext(ctx); ok {
+		return info.Scopes
+	}
+	return nil
+}
+
+// HasScopes reports whether the key that signed the request was granted
+// every one of scopes.
+func HasScopes(ctx context.Context, scopes ...string) bool {
+	granted := Scopes(ctx)
+	for _, s := range scopes {
+		if !contains(granted, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireScopes returns middleware that responds with 403 unless the key
+// that signed the request was granted every one of scopes.
+// It must be wrapped by a Validator's Handler.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScopes(r.Context(), scopes...) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// narrowScopes returns the scopes of a token, requested, granted to its key.
+func narrowScopes(granted, requested []string) []string {
+	var scopes []string
+	for _, s := range requested {
+		if contains(granted, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+func contains(l []string, s string) bool {
+	for _, o := range l {
+		if o == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import "sync"
+
+// Secret holds private key material in a buffer that is locked into memory,
+// where the platform supports it, so it is never written to swap, and that
+// is zeroed when wiped.
+type Secret struct {
+	mu     sync.Mutex
+	b      []byte
+	locked bool
+}
+
+// NewSecret copies b into a new locked buffer and zeroes b.
+func NewSecret(b []byte) *Secret {
+	s := &Secret{}
+	s.b, s.locked = lockedAlloc(len(b))
+	copy(s.b, b)
+	wipe(b)
+	return s
+}
+
+// Use calls fn with the secret's buffer, nil once wiped, holding it until fn
+// returns so it can't be wiped meanwhile. fn must not retain or modify it.
+func (s *Secret) Use(fn func(b []byte)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.b)
+}
+
+// String returns a copy of the secret.
+// The copy lives on the regular heap and can't be wiped.
+func (s *Secret) String() string {
+	var str string
+	s.Use(func(b []byte) {
+		str = string(b)
+	})
+	return str
+}
+
+// Wipe zeroes and releases the secret's buffer.
+func (s *Secret) Wipe() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.b == nil {
+		return
+	}
+	wipe(s.b)
+	lockedFree(s.b, s.locked)
+	s.b = nil
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// SecretStore is an in-memory key store that keeps private keys in Secrets.
+// Replacing or deleting a key wipes the previous secret.
+type SecretStore struct {
+	mu      sync.RWMutex
+	keys    map[string]*Secret
+	expires int
+}
+
+// NewSecretStore returns an empty store whose keys all use expires
+// as their expiration duration (see Validate).
+func NewSecretStore(expires int) *SecretStore {
+	return &SecretStore{keys: make(map[string]*Secret), expires: expires}
+}
+
+// Set stores pKey for key, wiping any previous secret. pKey is zeroed.
+func (s *SecretStore) Set(key string, pKey []byte) {
+	secret := NewSecret(pKey)
+	s.mu.Lock()
+	old := s.keys[key]
+	s.keys[key] = secret
+	s.mu.Unlock()
+	if old != nil {
+		old.Wipe()
+	}
+}
+
+// Delete removes and wipes the secret for key.
+func (s *SecretStore) Delete(key string) {
+	s.mu.Lock()
+	old := s.keys[key]
+	delete(s.keys, key)
+	s.mu.Unlock()
+	if old != nil {
+		old.Wipe()
+	}
+}
+
+// KeyFunc returns a KeyFunc backed by the store.
+//
+// KeyFuncs return secrets as strings, so each lookup copies the secret to
+// the regular heap (see Secret.String), where it stays, unlocked and
+// unwiped, until collected. The store only keeps secrets held between
+// lookups out of swap and memory dumps.
+func (s *SecretStore) KeyFunc() KeyFunc {
+	return func(key string) (string, int) {
+		// Copied under the store's lock, so a secret being replaced is
+		// either copied before it's wiped, or not looked up at all.
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		secret := s.keys[key]
+		if secret == nil {
+			return "", 0
+		}
+		return secret.String(), s.expires
+	}
+}
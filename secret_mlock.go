@@ -0,0 +1,33 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package hancock
+
+import "syscall"
+
+// lockedAlloc maps n bytes outside of the Go heap and locks them into memory.
+// It falls back to a regular slice when mapping or locking fails.
+func lockedAlloc(n int) ([]byte, bool) {
+	if n == 0 {
+		return []byte{}, false
+	}
+	b, err := syscall.Mmap(-1, 0, n, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return make([]byte, n), false
+	}
+	if err := syscall.Mlock(b); err != nil {
+		syscall.Munmap(b)
+		return make([]byte, n), false
+	}
+	return b, true
+}
+
+func lockedFree(b []byte, locked bool) {
+	if locked {
+		syscall.Munlock(b)
+		syscall.Munmap(b)
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package hancock
+
+func lockedAlloc(n int) ([]byte, bool) {
+	return make([]byte, n), false
+}
+
+func lockedFree(b []byte, locked bool) {}
@@ -0,0 +1,70 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSecretStore(t *testing.T) {
+	s := NewSecretStore(300)
+	pKey := []byte("secret")
+	s.Set("key", pKey)
+	if string(pKey) != "\x00\x00\x00\x00\x00\x00" {
+		t.Errorf("pKey not zeroed: %q", pKey)
+	}
+	s.Set("rotated", []byte("old"))
+	s.Set("rotated", []byte("new"))
+	s.Set("deleted", []byte("secret"))
+	s.Delete("deleted")
+
+	v := NewValidator(s.KeyFunc())
+	tests := []struct {
+		name   string
+		key    string
+		pKey   string
+		status int
+	}{
+		{"valid", "key", "secret", 0},
+		{"rotated", "rotated", "new", 0},
+		{"wiped on rotation", "rotated", "old", http.StatusUnauthorized},
+		{"deleted", "deleted", "secret", http.StatusUnauthorized},
+		{"unknown", "none", "secret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, Sign(http.MethodGet, tt.key, tt.pKey, "/", nil), nil)
+		if _, err := v.Validate(r); status(err) != tt.status {
+			t.Errorf("%s: status %d, want %d (%v)", tt.name, status(err), tt.status, err)
+		}
+	}
+}
+
+// TestSecretWipeRace wipes secrets while they are looked up, which faults
+// when lookups read unmapped buffers.
+func TestSecretWipeRace(t *testing.T) {
+	s := NewSecretStore(300)
+	keyFn := s.KeyFunc()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				s.Set("key", []byte("secret"+strconv.Itoa(j)))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				keyFn("key")
+			}
+		}()
+	}
+	wg.Wait()
+}
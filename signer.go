@@ -0,0 +1,47 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Signer signs with a key configured once, e.g. with the algorithm
+// (SignAlgorithm), version (SignVersion) and clock (SignClock), so the key
+// isn't threaded through every call site. Options given to its methods are
+// applied after its own.
+type Signer struct {
+	key     string
+	pKey    string
+	carrier Carrier
+	opts    []SignOption
+}
+
+// NewSigner returns a Signer signing as key with pKey, and requests with the
+// signing parameters attached by c, nil for the query string.
+func NewSigner(key, pKey string, c Carrier, opts ...SignOption) *Signer {
+	return &Signer{key: key, pKey: pKey, carrier: c, opts: append([]SignOption(nil), opts...)}
+}
+
+func (s *Signer) options(opts []SignOption) []SignOption {
+	return append(append([]SignOption(nil), s.opts...), opts...)
+}
+
+// SignURL returns a signed URL (see Sign).
+func (s *Signer) SignURL(method, urlStr string, qs url.Values, opts ...SignOption) string {
+	return Sign(method, s.key, s.pKey, urlStr, qs, s.options(opts)...)
+}
+
+// SignRequest signs r (see SignRequest).
+func (s *Signer) SignRequest(r *http.Request, opts ...SignOption) {
+	SignRequest(r, s.key, s.pKey, s.carrier, s.options(opts)...)
+}
+
+// SignValues returns a copy of values with the signing parameters added
+// (see SignQS).
+func (s *Signer) SignValues(method string, values url.Values, opts ...SignOption) url.Values {
+	return signValues(method, s.key, s.pKey, values, s.options(opts))
+}
@@ -0,0 +1,194 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Checker reports the health of something the Validator depends on, such as
+// its key store.
+type Checker interface {
+	Check() error
+}
+
+// CheckFunc adapts a function to a Checker.
+type CheckFunc func() error
+
+// Check calls fn.
+func (fn CheckFunc) Check() error {
+	return fn()
+}
+
+// CacheStats reports cache hits and misses, e.g. Quarantine.
+type CacheStats interface {
+	Stats() (hits, misses uint64)
+}
+
+type namedCheck struct {
+	name string
+	c    Checker
+}
+
+type namedCache struct {
+	name string
+	c    CacheStats
+}
+
+// WithCheck adds c to the checks reported by StatusHandler.
+func WithCheck(name string, c Checker) Option {
+	return func(v *Validator) {
+		v.checks = append(v.checks, namedCheck{name, c})
+	}
+}
+
+// WithCacheStats adds c to the caches reported by StatusHandler.
+func WithCacheStats(name string, c CacheStats) Option {
+	return func(v *Validator) {
+		v.caches = append(v.caches, namedCache{name, c})
+	}
+}
+
+// Status is the report written by StatusHandler.
+type Status struct {
+	OK        bool                   `json:"ok"`
+	Validated uint64                 `json:"validated"`
+	Failed    uint64                 `json:"failed"`
+	Checks    map[string]CheckStatus `json:"checks,omitempty"`
+	Caches    map[string]CacheStatus `json:"caches,omitempty"`
+	Pool      *PoolStats             `json:"pool,omitempty"`
+	Config    ConfigSummary          `json:"config"`
+
+	// Algorithms counts validated requests by the algorithms verifying
+	// them (see SignAlgorithms).
+	Algorithms map[string]uint64 `json:"algorithms,omitempty"`
+	// Mismatches counts signature mismatches by cause (see
+	// WithDiagnostics).
+	Mismatches map[string]uint64 `json:"mismatches,omitempty"`
+	// Legacy counts unsigned requests let through from legacy networks
+	// (see WithLegacyNetworks).
+	Legacy uint64 `json:"legacy,omitempty"`
+}
+
+// CheckStatus is the result of a Checker.
+type CheckStatus struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// CacheStatus summarizes a cache's effectiveness.
+type CacheStatus struct {
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+}
+
+// ConfigSummary describes a Validator's configuration, without any secrets.
+type ConfigSummary struct {
+	Params      Params   `json:"params"`
+	Expires     int      `json:"expires,omitempty"`
+	Exempt      []string `json:"exempt,omitempty"`
+	ErrorFormat string   `json:"errorFormat"`
+	Networks    []string `json:"networks,omitempty"`
+	Legacy      []string `json:"legacy,omitempty"`
+}
+
+// Status runs the Validator's checks and returns its status.
+func (v *Validator) Status() *Status {
+	s := &Status{
+		OK:        true,
+		Validated: v.stats.validated.Load(),
+		Failed:    v.stats.failed.Load(),
+		Config:    v.summary(),
+		Legacy:    v.stats.legacy.Load(),
+	}
+	if len(v.checks) > 0 {
+		s.Checks = make(map[string]CheckStatus)
+	}
+	for _, c := range v.checks {
+		st := CheckStatus{OK: true}
+		if err := c.c.Check(); err != nil {
+			st = CheckStatus{Error: err.Error()}
+			s.OK = false
+		}
+		s.Checks[c.name] = st
+	}
+	if len(v.caches) > 0 {
+		s.Caches = make(map[string]CacheStatus)
+	}
+	for _, c := range v.caches {
+		hits, misses := c.c.Stats()
+		st := CacheStatus{Hits: hits, Misses: misses}
+		if total := hits + misses; total > 0 {
+			st.HitRate = float64(hits) / float64(total)
+		}
+		s.Caches[c.name] = st
+	}
+	if v.pool != nil {
+		st := v.pool.Stats()
+		s.Pool = &st
+	}
+	s.Algorithms = counts(&v.stats.algs)
+	s.Mismatches = counts(&v.stats.mismatches)
+	return s
+}
+
+func (v *Validator) summary() ConfigSummary {
+	c := ConfigSummary{
+		Params:      v.params,
+		Expires:     v.expireSeconds(),
+		Exempt:      v.exempt,
+		ErrorFormat: "status",
+	}
+	if v.errors == ErrorJSON {
+		c.ErrorFormat = "json"
+	}
+	for _, p := range v.allowed {
+		c.Networks = append(c.Networks, p.String())
+	}
+	for _, p := range v.legacy {
+		c.Legacy = append(c.Legacy, p.String())
+	}
+	return c
+}
+
+// StatusHandler returns a handler writing the Validator's Status as JSON,
+// with a 503 status when any check fails, for readiness probes and
+// dashboards. Wrap it with Handler to require signed requests.
+func (v *Validator) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := v.Status()
+		w.Header().Set("Content-Type", "application/json")
+		if !s.OK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(s)
+	})
+}
+
+// count adds one to the count of name in m.
+func count(m *sync.Map, name string) {
+	n, ok := m.Load(name)
+	if !ok {
+		n, _ = m.LoadOrStore(name, new(atomic.Uint64))
+	}
+	n.(*atomic.Uint64).Add(1)
+}
+
+// counts returns the counts of m, nil when it's empty.
+func counts(m *sync.Map) map[string]uint64 {
+	var c map[string]uint64
+	m.Range(func(name, n interface{}) bool {
+		if c == nil {
+			c = make(map[string]uint64)
+		}
+		c[name.(string)] = n.(*atomic.Uint64).Load()
+		return true
+	})
+	return c
+}
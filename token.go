@@ -0,0 +1,72 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed tokens or mismatched token signatures.
+	ErrInvalidToken = errors.New("hancock: invalid token")
+	// ErrExpiredToken is returned for tokens past their expiration.
+	ErrExpiredToken = errors.New("hancock: expired token")
+)
+
+// signToken returns a `payload.signature` token for the JSON encoding of v.
+// The purpose is part of the signature so tokens of one kind can't be
+// passed off as another.
+func signToken(purpose, pKey string, v interface{}) string {
+	b, _ := json.Marshal(v)
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + tokenSignature(purpose, pKey, payload)
+}
+
+// decodeToken decodes the payload of token into v without verifying it.
+func decodeToken(token string, v interface{}) error {
+	payload, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidToken
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// verifyToken checks the signature of a token.
+func verifyToken(purpose, pKey, token string) error {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(tokenSignature(purpose, pKey, payload))) {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// verifyKeyToken verifies token was signed with any of info's secrets, so
+// tokens minted before a rotation are still accepted.
+func verifyKeyToken(purpose string, info *KeyInfo, token string) error {
+	for _, pKey := range info.secrets() {
+		if verifyToken(purpose, pKey, token) == nil {
+			return nil
+		}
+	}
+	return ErrInvalidToken
+}
+
+func tokenSignature(purpose, pKey, payload string) string {
+	hash := hmac.New(sha256.New, []byte(pKey))
+	hash.Write([]byte(purpose + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil))
+}
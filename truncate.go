@@ -0,0 +1,66 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hmac"
+	"hash"
+)
+
+// minTruncatedBits is the shortest truncated signature accepted.
+const minTruncatedBits = 64
+
+// SignTruncated signs with the HMAC truncated to its first bits bits
+// (a multiple of 8), unpadded, for short URLs sent by SMS or in QR codes.
+//
+// Truncating weakens signatures: each guess at an n bit signature succeeds
+// with a probability of 2^-n, so 128 bits are plenty, but 64 bits, the
+// least accepted, are only safe with strict rate limits and short expiry.
+// Validators only accept them for designated keys (see
+// WithTruncatedSignatures). Asymmetric signatures aren't truncated.
+func SignTruncated(bits int) SignOption {
+	return func(c *signConfig) {
+		c.truncate = bits / 8
+	}
+}
+
+// WithTruncatedSignatures accepts signatures truncated to at least minBits
+// bits (see SignTruncated) for keys, and full signatures only for the
+// others. minBits below 64 are raised to 64.
+func WithTruncatedSignatures(minBits int, keys ...string) Option {
+	return func(v *Validator) {
+		if minBits < minTruncatedBits {
+			minBits = minTruncatedBits
+		}
+		if v.truncated == nil {
+			v.truncated = make(map[string]int)
+		}
+		for _, k := range keys {
+			v.truncated[k] = minBits
+		}
+	}
+}
+
+// anyTruncated reports whether any of the signatures data is a truncated
+// signature of sig accepted for key.
+func (v *Validator) anyTruncated(key string, alg func() hash.Hash, sig, enc string, secrets, data []string) bool {
+	minBits, ok := v.truncated[key]
+	if !ok {
+		return false
+	}
+	matched := false
+	for _, pKey := range secrets {
+		hash := hmac.New(alg, []byte(pKey))
+		hash.Write([]byte(sig))
+		mac := hash.Sum(nil)
+		for _, d := range data {
+			b, err := decodeSignature(d, enc)
+			if err == nil && len(b)*8 >= minBits && len(b) < len(mac) && v.equal(mac[:len(b)], b) {
+				matched = true
+			}
+		}
+	}
+	return matched
+}
@@ -0,0 +1,94 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Unwrapper decrypts wrapped secrets, e.g. with a KMS, so key stores hold
+// ciphertext while requests are still verified locally.
+type Unwrapper interface {
+	Unwrap(wrapped string) (string, error)
+}
+
+// UnwrapperFunc is a function Unwrapper.
+type UnwrapperFunc func(wrapped string) (string, error)
+
+// Unwrap calls fn.
+func (fn UnwrapperFunc) Unwrap(wrapped string) (string, error) {
+	return fn(wrapped)
+}
+
+// WithUnwrapper treats the secrets of looked up keys, Secret and Previous,
+// as wrapped, unwrapping them with u when first used and caching them for
+// ttl. Failing to unwrap a secret fails its key's lookup.
+func WithUnwrapper(u Unwrapper, ttl time.Duration) Option {
+	return func(v *Validator) {
+		v.unwrap = &unwrapCache{u: u, ttl: ttl, secrets: make(map[string]unwrapped)}
+	}
+}
+
+type unwrapped struct {
+	secret  string
+	expires time.Time
+}
+
+// unwrapCache caches unwrapped secrets by their ciphertext.
+type unwrapCache struct {
+	u   Unwrapper
+	ttl time.Duration
+
+	mu      sync.Mutex
+	secrets map[string]unwrapped
+}
+
+func (c *unwrapCache) get(wrapped string, now time.Time) (string, error) {
+	c.mu.Lock()
+	s, ok := c.secrets[wrapped]
+	c.mu.Unlock()
+	if ok && now.Before(s.expires) {
+		return s.secret, nil
+	}
+
+	secret, err := c.u.Unwrap(wrapped)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Misses are rare, and slow anyway, sweep expired secrets.
+	for k, s := range c.secrets {
+		if !now.Before(s.expires) {
+			delete(c.secrets, k)
+		}
+	}
+	c.secrets[wrapped] = unwrapped{secret, now.Add(c.ttl)}
+	return secret, nil
+}
+
+// keyInfo returns the KeyInfo for key, its secrets unwrapped.
+func (v *Validator) keyInfo(key string) (*KeyInfo, error) {
+	info, err := v.keys(key)
+	if err != nil || info == nil || v.unwrap == nil {
+		return info, err
+	}
+	now := v.clock.Now()
+	unwrapped := *info
+	if info.Secret != "" {
+		if unwrapped.Secret, err = v.unwrap.get(info.Secret, now); err != nil {
+			return nil, fmt.Errorf("hancock: unwrapping secret of key `%s`: %w", KeyAlias(key), err)
+		}
+	}
+	unwrapped.Previous = make([]string, len(info.Previous))
+	for i, p := range info.Previous {
+		if unwrapped.Previous[i], err = v.unwrap.get(p, now); err != nil {
+			return nil, fmt.Errorf("hancock: unwrapping previous secret of key `%s`: %w", KeyAlias(key), err)
+		}
+	}
+	return &unwrapped, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// UploadPartsDigest returns a digest of the ordered part digests of a
+// multi-part upload.
+func UploadPartsDigest(parts []string) string {
+	hash := sha256.New()
+	for i, p := range parts {
+		fmt.Fprintf(hash, "%d:%s\n", i+1, p)
+	}
+	return base64.URLEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// SignUploadCompletion returns a signed completion URL bound to the upload
+// session started at init and to the digests of its parts, in order.
+func SignUploadCompletion(method, key, pKey, urlStr, session string, parts []string) string {
+	v := make(url.Values)
+	v.Set("upload", session)
+	v.Set("parts", UploadPartsDigest(parts))
+	return Sign(method, key, pKey, urlStr, v)
+}
+
+// ValidateUploadCompletion validates a completion request signed with
+// SignUploadCompletion against the session and the part digests the server
+// actually received.
+//
+// The url.Values returned are that of the request minus the signing and
+// upload parameters.
+func ValidateUploadCompletion(r *http.Request, pKey string, expireSeconds int, session string, parts []string) (url.Values, *Error) {
+	v, err := Validate(r, pKey, expireSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if v.Get("upload") != session {
+		return nil, newError(http.StatusForbidden, r, "upload session mismatch")
+	}
+	if !hmac.Equal([]byte(v.Get("parts")), []byte(UploadPartsDigest(parts))) {
+		return nil, newError(http.StatusForbidden, r, "upload parts mismatch")
+	}
+	v.Del("upload")
+	v.Del("parts")
+	return v, nil
+}
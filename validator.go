@@ -0,0 +1,716 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"hash"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Params names the signing query parameters.
+type Params struct {
+	APIKey    string `json:"apiKey"`
+	Timestamp string `json:"timestamp"`
+	Signature string `json:"signature"`
+	KeyHint   string `json:"keyHint"`
+	Version   string `json:"version"`
+	BodyHash  string `json:"bodyHash"`
+	Algorithm string `json:"algorithm"`
+	Nonce     string `json:"nonce"`
+	Expires   string `json:"expires"`
+	NotBefore string `json:"notBefore"`
+	Claims    string `json:"claims"`
+	MaxSize   string `json:"maxSize"`
+	Encoding  string `json:"encoding"`
+}
+
+// DefaultParams are the parameter names used unless configured otherwise.
+var DefaultParams = Params{APIKey: "apikey", Timestamp: "ts", Signature: "data", KeyHint: "kv", Version: "v", BodyHash: "bodyhash", Algorithm: "alg", Nonce: "nonce", Expires: "expires", NotBefore: "nbf", Claims: "claims", MaxSize: "maxsize", Encoding: "enc"}
+
+// withDefaults returns p with unset names taken from DefaultParams.
+func (p Params) withDefaults() Params {
+	d := DefaultParams
+	if p.APIKey == "" {
+		p.APIKey = d.APIKey
+	}
+	if p.Timestamp == "" {
+		p.Timestamp = d.Timestamp
+	}
+	if p.Signature == "" {
+		p.Signature = d.Signature
+	}
+	if p.KeyHint == "" {
+		p.KeyHint = d.KeyHint
+	}
+	if p.Version == "" {
+		p.Version = d.Version
+	}
+	if p.BodyHash == "" {
+		p.BodyHash = d.BodyHash
+	}
+	if p.Algorithm == "" {
+		p.Algorithm = d.Algorithm
+	}
+	if p.Nonce == "" {
+		p.Nonce = d.Nonce
+	}
+	if p.Expires == "" {
+		p.Expires = d.Expires
+	}
+	if p.NotBefore == "" {
+		p.NotBefore = d.NotBefore
+	}
+	if p.Claims == "" {
+		p.Claims = d.Claims
+	}
+	if p.MaxSize == "" {
+		p.MaxSize = d.MaxSize
+	}
+	if p.Encoding == "" {
+		p.Encoding = d.Encoding
+	}
+	return p
+}
+
+// names returns every signing parameter name.
+func (p Params) names() []string {
+	return []string{p.APIKey, p.Timestamp, p.Signature, p.KeyHint, p.Version, p.BodyHash, p.Algorithm, p.Nonce, p.Expires, p.NotBefore, p.Claims, p.MaxSize, p.Encoding}
+}
+
+// strip removes the signing parameters from q.
+func (p Params) strip(q url.Values) {
+	for _, n := range p.names() {
+		q.Del(n)
+	}
+}
+
+// ErrorFormat controls how Validator.Handler writes validation errors.
+type ErrorFormat int
+
+const (
+	// ErrorStatus writes only the status code.
+	ErrorStatus ErrorFormat = iota
+	// ErrorJSON writes the status code and the Error as JSON.
+	ErrorJSON
+)
+
+// Validator validates signed requests using the keys returned by a KeyFunc.
+type Validator struct {
+	keys    KeyInfoFunc
+	log     LogFunc
+	params  Params
+	expires int
+	policy  *ExpirePolicy
+	exempt  []string
+	errors  ErrorFormat
+	lenient bool
+	allowed []netip.Prefix
+	rawQS   bool
+	idem    IdempotencyStore
+	all     bool
+
+	controls   *Controls
+	clock      Clock
+	pageTokens bool
+	epoch      time.Duration
+	metrics    Metrics
+	route      string
+	limits     Limits
+	blind      []byte
+	bindHost   bool
+	host       string
+	paths      PathNormalization
+	bodyHash   bool
+	maxBody    int64
+	algs       []string
+	carrier    Carrier
+	env        string
+	versions   []string
+	drift      DriftFunc
+	maxDrift   time.Duration
+	warm       []string
+	pool       *ValidationPool
+	replay     ReplayStore
+	strict     bool
+	maxExpiry  time.Duration
+
+	deprecations  map[string]Deprecation
+	onDeprecated  DeprecationFunc
+	truncated     map[string]int
+	authorizers   []Authorizer
+	diagnostics   bool
+	canonicalizer Canonicalizer
+	unwrap        *unwrapCache
+	strictParams  bool
+
+	impersonation  []byte
+	onImpersonated ImpersonationFunc
+	dates          bool
+	service        string
+	contentDigest  bool
+	legacy         []netip.Prefix
+
+	checks []namedCheck
+	caches []namedCache
+	stats  *counters
+}
+
+// counters are shared by a Validator and its derived copies (see With).
+type counters struct {
+	validated  atomic.Uint64
+	failed     atomic.Uint64
+	algs       sync.Map // algorithm name to *atomic.Uint64
+	mismatches sync.Map // cause to *atomic.Uint64
+	legacy     atomic.Uint64
+}
+
+// Option configures a Validator.
+type Option func(*Validator)
+
+// defaultValidator backs the package level functions.
+var defaultValidator = NewValidator(nil)
+
+// NewValidator returns a Validator using keyFn to look up private keys.
+func NewValidator(keyFn KeyFunc, opts ...Option) *Validator {
+	v := &Validator{
+		log:     func(...interface{}) {},
+		params:  DefaultParams,
+		clock:   SystemClock{},
+		stats:   new(counters),
+		maxBody: DefaultMaxBodySize,
+	}
+	if keyFn != nil {
+		v.keys = keyFn.keyInfo
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// With returns a copy of v with opts applied, e.g. for a route needing a
+// different configuration. The copy shares v's status counters.
+func (v *Validator) With(opts ...Option) *Validator {
+	c := *v
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
+
+// WithLog sets the function validation errors are logged to.
+func WithLog(fn LogFunc) Option {
+	return func(v *Validator) {
+		if fn != nil {
+			v.log = fn
+		}
+	}
+}
+
+// WithKeyInfo looks up keys with fn instead of the KeyFunc.
+func WithKeyInfo(fn KeyInfoFunc) Option {
+	return func(v *Validator) {
+		v.keys = fn
+	}
+}
+
+// WithParams sets the names of the signing parameters.
+// Unset names keep their DefaultParams name.
+func WithParams(p Params) Option {
+	return func(v *Validator) {
+		v.params = p.withDefaults()
+	}
+}
+
+// WithExpires overrides the expiration duration returned by the KeyFunc
+// (see Validate for the meaning of expireSeconds).
+func WithExpires(expireSeconds int) Option {
+	return func(v *Validator) {
+		v.expires, v.policy = expireSeconds, nil
+	}
+}
+
+// WithExpirePolicy overrides the expiration durations and policies of keys
+// with p, e.g. Expires(5*time.Minute), rather than WithExpires' magic
+// numbers. Every request is rejected when p is nil or its window is under
+// a second.
+func WithExpirePolicy(p *ExpirePolicy) Option {
+	return func(v *Validator) {
+		if p == nil {
+			p = &ExpirePolicy{}
+		}
+		v.expires, v.policy = 0, p
+	}
+}
+
+// expireSeconds returns the expiration duration v overrides keys' with, 0
+// when it doesn't.
+func (v *Validator) expireSeconds() int {
+	if v.policy != nil {
+		return v.policy.seconds()
+	}
+	return v.expires
+}
+
+// WithExemptions skips validation for the given paths.
+// A path ending in "/" exempts every path beneath it.
+func WithExemptions(paths ...string) Option {
+	return func(v *Validator) {
+		v.exempt = append(v.exempt, paths...)
+	}
+}
+
+// WithErrorFormat sets how Handler writes validation errors.
+func WithErrorFormat(f ErrorFormat) Option {
+	return func(v *Validator) {
+		v.errors = f
+	}
+}
+
+// WithTolerantEncoding accepts signatures in standard or URL-safe base64,
+// with or without padding, instead of only padded URL-safe base64.
+func WithTolerantEncoding() Option {
+	return func(v *Validator) {
+		v.lenient = true
+	}
+}
+
+// WithAllowedNetworks only accepts requests from remote addresses within
+// prefixes (see ParsePrefixes).
+func WithAllowedNetworks(prefixes ...netip.Prefix) Option {
+	return func(v *Validator) {
+		v.allowed = append(v.allowed, prefixes...)
+	}
+}
+
+// WithRawQuery has Handler pass on the request's original query string, with
+// only the signing parameters spliced out, so the handler sees the exact
+// parameter order and encoding the client sent.
+func WithRawQuery() Option {
+	return func(v *Validator) {
+		v.rawQS = true
+	}
+}
+
+// WithAllErrors keeps validating after the first failed check, returning an
+// Error listing every failure in its Reasons, so clients can fix them all in
+// one round trip.
+func WithAllErrors() Option {
+	return func(v *Validator) {
+		v.all = true
+	}
+}
+
+// WithHostBinding requires signatures bound to the request's host (see
+// SignHost), so a URL signed for api.example.com can't be replayed against
+// staging.example.com. Behind proxies rewriting the Host header, host sets
+// the public host signatures are checked against instead.
+func WithHostBinding(host string) Option {
+	return func(v *Validator) {
+		v.bindHost = true
+		v.host = strings.ToLower(host)
+	}
+}
+
+// Validate looks up the private key for the request's API key, and validates
+// the request with it (see the package level Validate).
+func (v *Validator) Validate(r *http.Request) (url.Values, *Error) {
+	er, err := v.extract(r)
+	if err != nil {
+		return nil, err
+	}
+	q, _, err := v.validateKey(er)
+	keepBody(r, er)
+	return q, err
+}
+
+// Handler returns a handler that validates requests before invoking h.
+// The request's KeyInfo is available to h through FromContext.
+func (v *Validator) Handler(h http.Handler) http.Handler {
+	if v.idem != nil {
+		h = v.idempotent(h)
+	}
+	if v.metrics != nil {
+		return v.measured(h)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r, ok := v.admit(w, r); ok {
+			h.ServeHTTP(w, r)
+		}
+	})
+}
+
+// admit validates r, returning the request to pass on to the handler, or
+// false once it has written an error response.
+func (v *Validator) admit(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if v.isExempt(r.URL.Path) {
+		return r, true
+	}
+
+	r, err := v.extract(r)
+	if err != nil {
+		v.log(err)
+		v.writeError(w, err)
+		return nil, false
+	}
+
+	mode := Enforce
+	if v.controls != nil {
+		mode = v.controls.Mode(r.URL.Query().Get(v.params.APIKey))
+	}
+	if mode == RejectAll {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return nil, false
+	}
+	// Legacy traffic is let through only once the kill switch is checked.
+	if v.isLegacy(r) {
+		return r, true
+	}
+
+	if v.pool != nil {
+		if !v.pool.acquire(r.Context()) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return nil, false
+		}
+		defer v.pool.release()
+	}
+	_, info, err := v.validateKey(r)
+	if err != nil {
+		v.log(err)
+		if mode == Shadow {
+			return r, true
+		}
+		v.writeError(w, err)
+		return nil, false
+	}
+	claims, err := v.claims(r)
+	if err != nil {
+		v.log(err)
+		v.writeError(w, err)
+		return nil, false
+	}
+	if d, ok := v.deprecation(r); ok {
+		d.setHeaders(w.Header())
+	}
+	r = r.WithContext(withClaims(NewContext(r.Context(), info), claims))
+	if r, err = v.authorize(r, info); err != nil {
+		v.log(err)
+		v.writeError(w, err)
+		return nil, false
+	}
+	if v.rawQS {
+		u := *r.URL
+		u.RawQuery = v.StripQuery(u.RawQuery)
+		r.URL = &u
+	}
+	return r, true
+}
+
+func (v *Validator) validateKey(r *http.Request) (url.Values, *KeyInfo, *Error) {
+	key := r.URL.Query().Get(v.params.APIKey)
+	info, err := v.lookupKey(r, key)
+	if err != nil {
+		v.stats.failed.Add(1)
+		return nil, nil, err
+	}
+	expires := info.Expires
+	policy := info.Policy
+	if v.policy != nil {
+		policy = v.policy
+	} else if v.expires != 0 {
+		expires, policy = v.expires, nil
+	}
+	if policy != nil {
+		// A window rounding to 0 would fall back to the key's expiration
+		// duration.
+		if expires = policy.seconds(); expires == 0 {
+			v.stats.failed.Add(1)
+			return nil, nil, v.newError(http.StatusInternalServerError, r, "invalid expire policy, window %s", policy.Window+policy.Grace)
+		}
+	}
+	q, err := v.validate(r, info, expires)
+	if err != nil {
+		v.stats.failed.Add(1)
+	} else {
+		v.stats.validated.Add(1)
+		if d, ok := v.deprecation(r); ok && v.onDeprecated != nil {
+			v.onDeprecated(key, d)
+		}
+		if info.Impersonation != nil {
+			v.audit(r, info.Impersonation)
+		}
+		if rq := r.URL.Query(); policy != nil && rq.Get(v.params.Expires) == "" && rq.Get(v.params.NotBefore) == "" {
+			if ts := rq.Get(v.params.Timestamp); policy.inGrace(ts, v.clock.Now()) {
+				v.log("hancock: key", KeyAlias(key), "accepted in grace, timestamp", ts)
+			}
+		}
+	}
+	return q, info, err
+}
+
+// StripQuery removes the signing parameters from a raw query string, leaving
+// the remaining parameters byte for byte as they were.
+func (v *Validator) StripQuery(rawQuery string) string {
+	return StripParams(rawQuery, v.params.names()...)
+}
+
+// StripParams removes the named parameters from a raw query string, leaving
+// the remaining parameters byte for byte as they were.
+func StripParams(rawQuery string, names ...string) string {
+	var b strings.Builder
+	for _, pair := range strings.Split(rawQuery, "&") {
+		k, _, _ := strings.Cut(pair, "=")
+		if uk, err := url.QueryUnescape(k); err == nil && contains(names, uk) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(pair)
+	}
+	return b.String()
+}
+
+func (v *Validator) isExempt(path string) bool {
+	for _, p := range v.exempt {
+		if p == path || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Validator) writeError(w http.ResponseWriter, err *Error) {
+	if v.errors != ErrorJSON {
+		w.WriteHeader(err.Status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.Status)
+	json.NewEncoder(w).Encode(err)
+}
+
+// failures collects the errors of a validation.
+type failures struct {
+	all  bool
+	errs []*Error
+}
+
+// fail records err, and reports whether validation should stop.
+func (f *failures) fail(err *Error) bool {
+	f.errs = append(f.errs, err)
+	return !f.all
+}
+
+// err returns the first error, or when collecting all errors, an error
+// with the status of the first listing every failure.
+func (f *failures) err() *Error {
+	if len(f.errs) == 0 {
+		return nil
+	}
+	if !f.all {
+		return f.errs[0]
+	}
+	err := *f.errs[0]
+	err.Reasons = make([]string, len(f.errs))
+	for i, e := range f.errs {
+		err.Reasons[i] = e.Message
+	}
+	err.Message = strings.Join(err.Reasons, "; ")
+	return &err
+}
+
+func (v *Validator) validate(r *http.Request, info *KeyInfo, expireSeconds int) (url.Values, *Error) {
+	if err := ctxError(r); err != nil {
+		return nil, err
+	}
+	if err := v.checkQuery(r); err != nil {
+		return nil, err
+	}
+	if len(v.allowed) > 0 {
+		if a, err := RemoteAddr(r); err != nil || !matchAddr(v.allowed, a) {
+			return nil, v.newError(http.StatusForbidden, r, "remote address not allowed")
+		}
+	}
+	if err := v.checkEnvironment(r, info); err != nil {
+		return nil, err
+	}
+
+	p := v.params
+	q := r.URL.Query()
+	if err := v.checkParams(r, q); err != nil {
+		return nil, err
+	}
+	f := failures{all: v.all}
+	switch expireSeconds {
+	default: // Validate expire seconds is in range, -1 ignoring only the key's window
+		ts := q.Get(p.Timestamp)
+		if nbf := q.Get(p.NotBefore); nbf != "" {
+			if err := v.checkNotBefore(r, ts, nbf); err != nil && f.fail(err) {
+				return nil, f.err()
+			}
+			// The request is valid for its window from when it becomes valid.
+			ts = nbf
+		}
+		if exp := q.Get(p.Expires); exp != "" {
+			if err := v.checkExpires(r, ts, exp, expireSeconds); err != nil && f.fail(err) {
+				return nil, f.err()
+			}
+		} else if expireSeconds != -1 {
+			if s, ok := isValidTS(ts, expireSeconds, v.clock.Now()); !ok && f.fail(v.newError(http.StatusNotAcceptable, r, "%s timestamp %s", s, ts)) {
+				return nil, f.err()
+			}
+		}
+	case -2: // Disable security altogether
+		p.strip(q)
+		return q, nil
+	}
+
+	// The version is signed along with the query, so can't be downgraded.
+	fm, ok := v.formatFor(q.Get(p.Version))
+	if !ok {
+		f.fail(v.newError(http.StatusBadRequest, r, "unsupported version %q", q.Get(p.Version)))
+		return nil, f.err()
+	}
+
+	algName := q.Get(p.Algorithm)
+	verify, asymmetric := v.schemeFor(algName)
+	hashes, ok := v.hashesFor(algName)
+	if !ok {
+		f.fail(v.newError(http.StatusBadRequest, r, "too many algorithms"))
+		return nil, f.err()
+	}
+	if !asymmetric && len(hashes) == 0 {
+		f.fail(v.newError(http.StatusBadRequest, r, "unsupported algorithm %q", algName))
+		return nil, f.err()
+	}
+
+	enc := q.Get(p.Encoding)
+	if !knownEncoding(enc) {
+		f.fail(v.newError(http.StatusBadRequest, r, "unsupported encoding %q", enc))
+		return nil, f.err()
+	}
+
+	// Generate `METHOD:QUERY_STRING` string for hashing (removing signature param)
+	data := q[p.Signature]
+	q.Del(p.Signature)
+	var idemKey string
+	if v.idem != nil {
+		idemKey = r.Header.Get(IdempotencyKeyHeader)
+	}
+	var host string
+	if v.bindHost {
+		if host = v.host; host == "" {
+			host = requestHost(r)
+		}
+	}
+	req := &CanonicalRequest{
+		Method:         r.Method,
+		Host:           host,
+		Path:           v.paths.apply(r.URL.EscapedPath()),
+		Query:          q,
+		IdempotencyKey: idemKey,
+		Header:         r.Header,
+	}
+	sig := canonicalizer(v.canonicalizer, fm).Canonical(req)
+
+	// Validate hash, any signature with any of the key's secrets will do
+	// (clients sign with both old and new secrets during rotation).
+	var secrets []string
+	if !asymmetric && info.Secret != "" {
+		secrets = v.dateSecrets(v.secrets(info, q.Get(p.KeyHint)), q.Get(p.Timestamp))
+	}
+	// Clients send at most a signature per algorithm and secret, checked
+	// before any is verified.
+	if max := len(hashes) * len(secrets); len(data) > max && len(data) > 1 {
+		f.fail(v.newError(http.StatusBadRequest, r, "too many signatures"))
+		return nil, f.err()
+	}
+	var matched bool
+	if asymmetric {
+		matched = anyAsymmetric(verify, info.PublicKey, sig, data)
+		if matched {
+			count(&v.stats.algs, algName)
+		}
+	} else if info.Secret != "" {
+		// Keys holding only a public key can't be used with HMAC, an empty
+		// secret would sign anything.
+		for _, alg := range hashes {
+			if v.anySignature(alg.fn, sig, enc, secrets, data) ||
+				v.anyTruncated(q.Get(p.APIKey), alg.fn, sig, enc, secrets, data) {
+				matched = true
+				count(&v.stats.algs, alg.name)
+			}
+		}
+	}
+	// Mismatches are diagnosed under the canonical string versions only.
+	if !matched && v.diagnostics && v.canonicalizer == nil && !asymmetric && info.Secret != "" {
+		cause := v.diagnose(r, req, fm, hashes, enc, info, q.Get(p.KeyHint), data)
+		count(&v.stats.mismatches, cause)
+		v.log("hancock: signature mismatch, key", KeyAlias(q.Get(p.APIKey)), "cause", cause)
+	}
+	if !matched {
+		// The expected signature is left out, the error may be written to the client.
+		f.fail(v.newError(http.StatusUnauthorized, r, "signature mismatch"))
+	} else if err := v.checkDevice(r, info, data); err != nil {
+		f.fail(err)
+	} else if err := v.checkMaxSize(r, q.Get(p.MaxSize)); err != nil {
+		f.fail(err)
+	} else if err := v.checkBodyHash(r, q.Get(p.BodyHash)); err != nil {
+		f.fail(err)
+	} else if err := v.checkContentDigest(r, q.Get(p.BodyHash)); err != nil {
+		f.fail(err)
+	} else if err := v.checkNonce(r, q.Get(p.APIKey), q.Get(p.Nonce), acceptedUntil(q.Get(p.Timestamp), q.Get(p.NotBefore), q.Get(p.Expires), expireSeconds)); err != nil {
+		f.fail(err)
+	}
+	if err := f.err(); err != nil {
+		return nil, err
+	}
+
+	// Remove remaining signature params
+	p.strip(q)
+	return q, nil
+}
+
+func (v *Validator) anySignature(alg func() hash.Hash, sig, enc string, secrets, data []string) bool {
+	ok := false
+	for _, pKey := range secrets {
+		hash := hmac.New(alg, []byte(pKey))
+		hash.Write([]byte(sig))
+		mac := hash.Sum(nil)
+		for _, d := range data {
+			if v.signatureEqual(mac, d, enc) {
+				ok = true
+			}
+		}
+	}
+	return ok
+}
+
+// signatureEqual compares mac against the signature, encoded with enc, in
+// constant time.
+func (v *Validator) signatureEqual(mac []byte, sig, enc string) bool {
+	if enc != "" && enc != Base64URL {
+		b, err := decodeSignature(sig, enc)
+		return err == nil && v.equal(mac, b)
+	}
+	if !v.lenient {
+		return v.equal([]byte(base64.URLEncoding.EncodeToString(mac)), []byte(sig))
+	}
+	// Unescaped '+' arrives as ' ' from the query string.
+	sig = strings.NewReplacer("+", "-", " ", "-", "/", "_").Replace(strings.TrimRight(sig, "="))
+	b, err := base64.RawURLEncoding.DecodeString(sig)
+	return err == nil && v.equal(mac, b)
+}
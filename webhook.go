@@ -0,0 +1,198 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Webhook headers set by SignWebhook.
+const (
+	WebhookKeyHeader       = "X-Hancock-Key"
+	WebhookTimestampHeader = "X-Hancock-Timestamp"
+	WebhookEventHeader     = "X-Hancock-Event-Id"
+	WebhookAttemptHeader   = "X-Hancock-Attempt"
+	WebhookOriginalHeader  = "X-Hancock-Original-Timestamp"
+	WebhookSignatureHeader = "X-Hancock-Signature"
+)
+
+// ErrWebhookSignature is returned for webhooks whose signature doesn't match.
+var ErrWebhookSignature = errors.New("hancock: webhook signature mismatch")
+
+// WebhookMeta describes a webhook delivery. Each field is signed, so
+// receivers can trust it to dedupe and order deliveries.
+type WebhookMeta struct {
+	// EventID identifies the event, and is the same across retries.
+	EventID string
+	// Attempt is the delivery attempt, starting at 1.
+	Attempt int
+	// Original is the time of the first delivery attempt.
+	Original time.Time
+	// Sent is the time of this delivery attempt.
+	Sent time.Time
+}
+
+// SignWebhook signs the outgoing webhook r, with the given body, setting
+// the webhook headers. Zero Sent and Original times default to now, of the
+// clock set by opts (see SignClock).
+func SignWebhook(r *http.Request, key, pKey string, body []byte, meta WebhookMeta, opts ...SignOption) {
+	if meta.Sent.IsZero() {
+		meta.Sent = signNow(opts)
+	}
+	if meta.Original.IsZero() {
+		meta.Original = meta.Sent
+	}
+	if meta.Attempt == 0 {
+		meta.Attempt = 1
+	}
+	r.Header.Set(WebhookKeyHeader, key)
+	r.Header.Set(WebhookTimestampHeader, strconv.FormatInt(meta.Sent.Unix(), 10))
+	r.Header.Set(WebhookEventHeader, meta.EventID)
+	r.Header.Set(WebhookAttemptHeader, strconv.Itoa(meta.Attempt))
+	r.Header.Set(WebhookOriginalHeader, strconv.FormatInt(meta.Original.Unix(), 10))
+	r.Header.Set(WebhookSignatureHeader, webhookSignature(pKey, r.Header, body))
+}
+
+// SignWebhookTrailer is SignWebhook for bodies streamed from body, whose
+// signature is sent as a trailer once body is read to EOF. The request must
+// be sent over HTTP/2, or HTTP/1.1 with chunked encoding.
+func SignWebhookTrailer(r *http.Request, key, pKey string, body io.Reader, meta WebhookMeta, opts ...SignOption) {
+	SignWebhook(r, key, pKey, nil, meta, opts...)
+	r.Header.Del(WebhookSignatureHeader)
+	if r.Trailer == nil {
+		r.Trailer = make(http.Header)
+	}
+	r.Trailer.Set(WebhookSignatureHeader, "")
+	r.ContentLength = -1
+	r.Body = &trailerSigner{r: r, pKey: pKey, body: body, digest: sha256.New()}
+}
+
+// trailerSigner sets the webhook signature trailer once its body is read.
+type trailerSigner struct {
+	r      *http.Request
+	pKey   string
+	body   io.Reader
+	digest hash.Hash
+}
+
+func (t *trailerSigner) Read(p []byte) (int, error) {
+	n, err := t.body.Read(p)
+	t.digest.Write(p[:n])
+	if err == io.EOF {
+		t.r.Trailer.Set(WebhookSignatureHeader, webhookDigestSignature(t.pKey, t.r.Header, t.digest.Sum(nil)))
+	}
+	return n, err
+}
+
+func (t *trailerSigner) Close() error {
+	if c, ok := t.body.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// VerifyWebhook verifies a webhook signed with SignWebhook, sent within
+// maxAge, of at most maxSize bytes, returning its metadata and body. r.Body
+// is replaced so it can be read again. The signature is read from the
+// trailers when it isn't in the headers (see SignWebhookTrailer).
+func VerifyWebhook(r *http.Request, keys KeyInfoFunc, maxAge time.Duration, maxSize int64) (*WebhookMeta, []byte, error) {
+	return verifyWebhook(r, keys, maxAge, maxSize, SystemClock{})
+}
+
+// VerifyWebhook is VerifyWebhook with v's keys and clock.
+func (v *Validator) VerifyWebhook(r *http.Request, maxAge time.Duration, maxSize int64) (*WebhookMeta, []byte, error) {
+	return verifyWebhook(r, v.keys, maxAge, maxSize, v.clock)
+}
+
+func verifyWebhook(r *http.Request, keys KeyInfoFunc, maxAge time.Duration, maxSize int64, clock Clock) (*WebhookMeta, []byte, error) {
+	// The key is looked up first, bodies of unknown keys aren't read.
+	key := r.Header.Get(WebhookKeyHeader)
+	if keys == nil {
+		return nil, nil, fmt.Errorf("hancock: unknown webhook key `%s`", KeyAlias(key))
+	}
+	info, err := keys(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info == nil || info.Secret == "" {
+		return nil, nil, fmt.Errorf("hancock: unknown webhook key `%s`", KeyAlias(key))
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxSize))
+	r.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	sig := []byte(headerOrTrailer(r, WebhookSignatureHeader))
+	ok := false
+	for _, pKey := range info.secrets() {
+		if hmac.Equal([]byte(webhookSignature(pKey, r.Header, body)), sig) {
+			ok = true
+		}
+	}
+	if !ok {
+		return nil, nil, ErrWebhookSignature
+	}
+
+	meta, err := parseWebhookMeta(r.Header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if age := clock.Now().Sub(meta.Sent); age > maxAge || age < -maxAge {
+		return nil, nil, ErrExpiredToken
+	}
+	return meta, body, nil
+}
+
+func parseWebhookMeta(h http.Header) (*WebhookMeta, error) {
+	sent, err := strconv.ParseInt(h.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("hancock: invalid webhook timestamp: %s", err)
+	}
+	orig, err := strconv.ParseInt(h.Get(WebhookOriginalHeader), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("hancock: invalid webhook original timestamp: %s", err)
+	}
+	attempt, err := strconv.Atoi(h.Get(WebhookAttemptHeader))
+	if err != nil {
+		return nil, fmt.Errorf("hancock: invalid webhook attempt: %s", err)
+	}
+	return &WebhookMeta{
+		EventID:  h.Get(WebhookEventHeader),
+		Attempt:  attempt,
+		Original: time.Unix(orig, 0),
+		Sent:     time.Unix(sent, 0),
+	}, nil
+}
+
+func webhookSignature(pKey string, h http.Header, body []byte) string {
+	digest := sha256.Sum256(body)
+	return webhookDigestSignature(pKey, h, digest[:])
+}
+
+func webhookDigestSignature(pKey string, h http.Header, digest []byte) string {
+	hash := hmac.New(sha256.New, []byte(pKey))
+	fmt.Fprintf(hash, "webhook\n%s\n%s\n%s\n%s\n%s\n%s",
+		h.Get(WebhookKeyHeader),
+		h.Get(WebhookTimestampHeader),
+		h.Get(WebhookEventHeader),
+		h.Get(WebhookAttemptHeader),
+		h.Get(WebhookOriginalHeader),
+		hex.EncodeToString(digest))
+	return base64.URLEncoding.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,77 @@
+// Copyright 2014 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hancock
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readCounter counts the bytes read from r.
+type readCounter struct {
+	r io.Reader
+	n int
+}
+
+func (c *readCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	const body = `{"event": "paid"}`
+	clock := newTestClock()
+	v := NewValidator(nil, WithClock(clock), WithKeyInfo(keyMap(map[string]*KeyInfo{
+		"key": {Key: "key", Secret: "secret", Previous: []string{"old"}},
+	})))
+	tests := []struct {
+		name    string
+		key     string
+		pKey    string
+		sent    string
+		age     time.Duration
+		maxSize int64
+		err     bool
+		read    bool // whether the body is read
+	}{
+		{"valid", "key", "secret", body, 0, 1 << 10, false, true},
+		{"previous secret", "key", "old", body, 0, 1 << 10, false, true},
+		{"tampered", "key", "secret", `{"event": "refunded"}`, 0, 1 << 10, true, true},
+		{"wrong secret", "key", "other", body, 0, 1 << 10, true, true},
+		{"unknown key", "none", "secret", body, 0, 1 << 10, true, false},
+		{"too large", "key", "secret", body, 0, 4, true, true},
+		{"old", "key", "secret", body, 10 * time.Minute, 1 << 10, true, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/hooks", nil)
+		SignWebhook(r, tt.key, tt.pKey, []byte(body), WebhookMeta{EventID: "evt", Sent: clock.now.Add(-tt.age)})
+		rc := &readCounter{r: strings.NewReader(tt.sent)}
+		r.Body = io.NopCloser(rc)
+		meta, b, err := v.VerifyWebhook(r, 5*time.Minute, tt.maxSize)
+		if (err != nil) != tt.err || (rc.n > 0) != tt.read {
+			t.Errorf("%s: err %v, read %d bytes", tt.name, err, rc.n)
+			continue
+		}
+		if err == nil && (meta.EventID != "evt" || string(b) != body) {
+			t.Errorf("%s: %+v %q", tt.name, meta, b)
+		}
+	}
+}
+
+func TestVerifyWebhookSize(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader("too large"))
+	SignWebhook(r, "key", "secret", []byte("too large"), WebhookMeta{})
+	keys := keyMap(map[string]*KeyInfo{"key": {Key: "key", Secret: "secret"}})
+	var maxErr *http.MaxBytesError
+	if _, _, err := VerifyWebhook(r, keys, time.Minute, 4); !errors.As(err, &maxErr) {
+		t.Errorf("err %v, want a MaxBytesError", err)
+	}
+}
@@ -1,3 +1,7 @@
+// Package wrappers adapts hancock validation to dingo handlers.
+//
+// It's kept separate from hancock so the core package stays free of the
+// dingo dependency.
 package wrappers
 
 import (
@@ -18,13 +22,13 @@ func WrapSigned(keyFunc KeyFunc, expireSeconds int, handlerFunc rest.Handler) re
 		key := ctx.URL.Query().Get("apikey")
 		pKey, err := keyFunc(key)
 		if err != nil {
-			log.Printf("API key retrieval failed: `%s`; %s", key, err)
+			log.Printf("API key retrieval failed: `%s`; %s", hancock.KeyAlias(key), err)
 			return http.StatusUnauthorized, nil
 		}
 
 		qs, hErr := hancock.Validate(ctx.Request, pKey, expireSeconds)
 		if hErr != nil {
-			log.Printf("URL validation failed for query-string: `%s`; %s", ctx.URL.RawQuery, hErr)
+			log.Printf("URL validation failed for key: `%s`; %s", hancock.KeyAlias(key), hErr)
 			return hErr.Status, nil
 		}
 